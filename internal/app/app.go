@@ -7,7 +7,6 @@ import (
 	"lb/internal/config"
 	routes2 "lb/internal/modules"
 	"lb/internal/modules/backends"
-	"lb/internal/modules/backends/models"
 	"lb/internal/modules/healthchecker"
 	"lb/internal/modules/loadBalancer"
 	rateLimiter2 "lb/internal/modules/rateLimiter"
@@ -21,12 +20,16 @@ import (
 // Глобальный логгер приложения
 var Logger *zap.Logger
 
-// NewApp инициализирует и запускает все компоненты load balancer'а
+// NewApp инициализирует и запускает все компоненты load balancer'а.
+// Блокируется до сигнала завершения и возвращает управление после graceful shutdown.
 // configPath - путь к конфигурационному файлу (без расширения)
 func NewApp(configPath string) {
 	// Инициализация логгера
 	InitLogger()
-	ctx := context.Background()
+	// Контекст приложения отменяется после graceful shutdown и останавливает
+	// фоновые задачи: пополнение rate limiter и перечитывание сертификатов
+	ctx, stop := context.WithCancel(context.Background())
+	defer stop()
 	mylogger, _ := zap.NewDevelopment()
 	sugar := mylogger.Sugar()
 
@@ -71,41 +74,65 @@ func NewApp(configPath string) {
 	sugar.Info("Health checker initialized")
 
 	// Загрузка маршрутов из конфигурации
-	routes := make([]loadBalancer.RouteConfig, len(config.Routes))
-	for i, route := range config.Routes {
-		routes[i] = loadBalancer.RouteConfig{
-			Path:     route.Path,
-			Backends: make([]models.Backend, len(route.Backends)),
-		}
-		for j, b := range route.Backends {
-			routes[i].Backends[j] = models.Backend{
-				URL:    b.URL,
-				Health: b.Health,
-			}
-		}
+	routes := routeConfigs(config.Routes)
+	for _, route := range routes {
 		sugar.Infof("Loaded route %s with %d backends", route.Path, len(route.Backends))
 	}
 
 	// Создание балансировщиков нагрузки
-	lbMap := loadBalancer.CreateLoadBalancers(routes, backend, hc, Logger)
+	lbMap, err := loadBalancer.CreateLoadBalancers(routes, backend, hc, Logger)
+	if err != nil {
+		sugar.Fatalf("Error creating load balancers: %v", err)
+	}
 	sugar.Infof("Creating load balancer map for routes: %v", routes)
-	// Инициализация rate limiter
+	// Инициализация rate limiter. Пополнение токенов запускает сам конструктор,
+	// отдельный вызов StartPeriod не нужен: второй цикл удвоил бы скорость пополнения.
 	rateLimiter := rateLimiter2.NewTokenBucketLimiter(ctx, config.RateLimiter.Limit, time.Second*30, Logger)
 	sugar.Info("Load balancers and rate limiter initialized")
 
-	// Настройка HTTP сервера
+	// Настройка HTTP сервера. Потоки событий admin API завершаются в начале
+	// server.Shutdown, иначе открытый поток задерживал бы остановку до таймаута.
+	adminCtx, stopAdminStreams := context.WithCancel(ctx)
 	server := &http.Server{
 		Addr:    config.LoadBalancer.Address,
-		Handler: routes2.CreateRouter(lbMap, rateLimiter, Logger),
+		Handler: routes2.CreateRouter(adminCtx, lbMap, rateLimiter, backend, hc, config.Admin.Token, Logger),
 	}
+	server.RegisterOnShutdown(stopAdminStreams)
 	sugar.Infof("Server created with address %s", config.LoadBalancer.Address)
 
-	// Запуск сервера в отдельной горутине
-	go func() {
-		if err := server.ListenAndServe(); err != nil && err != http.ErrServerClosed {
-			sugar.Errorf("Server failed: %v", err)
+	// Запуск сервера в отдельной горутине: HTTPS с выбором сертификата по SNI или обычный HTTP
+	servers := []*http.Server{server}
+	if config.LoadBalancer.TLS.Enabled {
+		if err := configureTLS(ctx, server, config.LoadBalancer.TLS, Logger); err != nil {
+			sugar.Fatalf("Error configuring TLS: %v", err)
 		}
-	}()
+		go func() {
+			if err := server.ListenAndServeTLS("", ""); err != nil && err != http.ErrServerClosed {
+				sugar.Errorf("Server failed: %v", err)
+			}
+		}()
+
+		// Дополнительный HTTP-листенер, перенаправляющий клиентов на HTTPS
+		if config.LoadBalancer.RedirectAddress != "" {
+			redirectServer := &http.Server{
+				Addr:    config.LoadBalancer.RedirectAddress,
+				Handler: httpsRedirectHandler(config.LoadBalancer.Address),
+			}
+			servers = append(servers, redirectServer)
+			go func() {
+				if err := redirectServer.ListenAndServe(); err != nil && err != http.ErrServerClosed {
+					sugar.Errorf("Redirect server failed: %v", err)
+				}
+			}()
+			sugar.Infof("HTTP to HTTPS redirect listening on %s", config.LoadBalancer.RedirectAddress)
+		}
+	} else {
+		go func() {
+			if err := server.ListenAndServe(); err != nil && err != http.ErrServerClosed {
+				sugar.Errorf("Server failed: %v", err)
+			}
+		}()
+	}
 	sugar.Info(">>>>Server started<<<<")
 
 	// Добавление клиента rate limiter
@@ -114,15 +141,14 @@ func NewApp(configPath string) {
 		Capacity: config.RateLimiter.Limit,
 		Interval: time.Second * 30,
 	})
-	rateLimiter.StartPeriod(ctx)
-	sugar.Info("Rate limiter client added and started")
+	sugar.Info("Rate limiter client added")
 
 	// Запуск health checker
 	go hc.Start()
 	sugar.Info("Health checker started")
 
-	// Обработка graceful shutdown
-	go handleShutdown(ctx, server, sugar)
+	// Обработка graceful shutdown: блокирует NewApp до сигнала завершения
+	handleShutdown(ctx, servers, lbMap, sugar)
 }
 
 // InitLogger настраивает глобальный логгер приложения
@@ -140,7 +166,9 @@ func InitLogger() {
 }
 
 // handleShutdown обрабатывает сигналы завершения работы приложения
-func handleShutdown(ctx context.Context, server *http.Server, sugar *zap.SugaredLogger) {
+// После остановки серверов дожидается (или закрывает) WebSocket и другие Upgrade-соединения,
+// которые http.Server.Shutdown не отслеживает.
+func handleShutdown(ctx context.Context, servers []*http.Server, lbMap map[string]*loadBalancer.LoadBalancerHandler, sugar *zap.SugaredLogger) {
 	sigChan := make(chan os.Signal, 1)
 	signal.Notify(sigChan, syscall.SIGINT, syscall.SIGTERM)
 
@@ -151,10 +179,18 @@ func handleShutdown(ctx context.Context, server *http.Server, sugar *zap.Sugared
 	shutdownCtx, cancel := context.WithTimeout(ctx, 30*time.Second)
 	defer cancel()
 
-	// Останавливаем сервер
-	if err := server.Shutdown(shutdownCtx); err != nil {
-		sugar.Errorf("Shutdown error: %v", err)
-	} else {
-		sugar.Info("Server stopped gracefully")
+	// Останавливаем серверы
+	for _, server := range servers {
+		if err := server.Shutdown(shutdownCtx); err != nil {
+			sugar.Errorf("Shutdown error: %v", err)
+		} else {
+			sugar.Infof("Server %s stopped gracefully", server.Addr)
+		}
+	}
+
+	for path, handler := range lbMap {
+		if closed := handler.DrainUpgrades(shutdownCtx); closed > 0 {
+			sugar.Warnf("Force-closed %d upgraded connections on route %s", closed, path)
+		}
 	}
 }
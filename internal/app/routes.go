@@ -0,0 +1,161 @@
+package app
+
+import (
+	"lb/internal/config"
+	"lb/internal/modules/backends/models"
+	"lb/internal/modules/loadBalancer"
+)
+
+// routeConfigs переводит маршруты из конфигурации в конфигурацию балансировщика.
+// Бэкенды групп приоритета и пулов переносятся в общий список маршрута
+// с пометкой группы или пула. Корректность настроек проверяет CreateLoadBalancers.
+func routeConfigs(routes []config.Route) []loadBalancer.RouteConfig {
+	result := make([]loadBalancer.RouteConfig, len(routes))
+	for i, route := range routes {
+		result[i] = loadBalancer.RouteConfig{
+			Path:             route.Path,
+			Algorithm:        route.Algorithm,
+			AlgorithmOptions: route.AlgorithmOptions,
+			HashKey:          route.HashKey,
+			SlowStart:        route.SlowStart,
+			Sticky: loadBalancer.StickyConfig{
+				Enabled:    route.Sticky.Enabled,
+				CookieName: route.Sticky.CookieName,
+				TTL:        route.Sticky.TTL,
+				SigningKey: route.Sticky.SigningKey,
+			},
+			PoolOverride: loadBalancer.PoolOverride{
+				Header: route.PoolOverride.Header,
+				Cookie: route.PoolOverride.Cookie,
+			},
+			Mirror: loadBalancer.MirrorConfig{
+				URL:       route.Mirror.URL,
+				Percent:   route.Mirror.Percent,
+				QueueSize: route.Mirror.QueueSize,
+			},
+			BodyBuffering: loadBalancer.BodyBufferingConfig{
+				MaxMemory:   route.BodyBuffering.MaxMemory,
+				SpoolToDisk: route.BodyBuffering.SpoolToDisk,
+				SpoolDir:    route.BodyBuffering.SpoolDir,
+			},
+			Streaming: loadBalancer.StreamingConfig{
+				FlushInterval: route.Streaming.FlushInterval,
+				IdleTimeout:   route.Streaming.IdleTimeout,
+			},
+			Headers: loadBalancer.ProxyHeadersConfig{
+				Host:           route.Headers.Host,
+				TrustedProxies: route.Headers.TrustedProxies,
+				Forwarded:      route.Headers.Forwarded,
+			},
+			Retry: retryPolicy(route.Retry),
+			Hedge: loadBalancer.HedgeConfig{
+				Enabled:     route.Hedge.Enabled,
+				Delay:       route.Hedge.Delay,
+				Percentile:  route.Hedge.Percentile,
+				MaxInFlight: route.Hedge.MaxInFlight,
+			},
+			CircuitBreaker: loadBalancer.CircuitBreakerConfig{
+				Enabled:             route.CircuitBreaker.Enabled,
+				ConsecutiveFailures: route.CircuitBreaker.ConsecutiveFailures,
+				ErrorRate:           route.CircuitBreaker.ErrorRate,
+				MinRequests:         route.CircuitBreaker.MinRequests,
+				Window:              route.CircuitBreaker.Window,
+				OpenTimeout:         route.CircuitBreaker.OpenTimeout,
+				HalfOpenRequests:    route.CircuitBreaker.HalfOpenRequests,
+			},
+			OutlierDetection: loadBalancer.OutlierDetectionConfig{
+				Enabled:                  route.OutlierDetection.Enabled,
+				Consecutive5xx:           route.OutlierDetection.Consecutive5xx,
+				ConsecutiveGatewayErrors: route.OutlierDetection.ConsecutiveGatewayErrors,
+				LatencyFactor:            route.OutlierDetection.LatencyFactor,
+				BaseEjectionTime:         route.OutlierDetection.BaseEjectionTime,
+				MaxEjectionTime:          route.OutlierDetection.MaxEjectionTime,
+				MaxEjectionPercent:       route.OutlierDetection.MaxEjectionPercent,
+			},
+			Backends: make([]models.Backend, 0, len(route.Backends)),
+		}
+		for _, b := range route.Backends {
+			result[i].Backends = append(result[i].Backends, backendModel(b, "", ""))
+		}
+		for _, g := range route.Groups {
+			result[i].Groups = append(result[i].Groups, loadBalancer.BackendGroup{
+				Name:       g.Name,
+				Priority:   g.Priority,
+				MinHealthy: g.MinHealthy,
+			})
+			for _, b := range g.Backends {
+				result[i].Backends = append(result[i].Backends, backendModel(b, g.Name, ""))
+			}
+		}
+		for _, p := range route.Pools {
+			result[i].Pools = append(result[i].Pools, loadBalancer.BackendPool{
+				Name:    p.Name,
+				Percent: p.Percent,
+			})
+			for _, b := range p.Backends {
+				result[i].Backends = append(result[i].Backends, backendModel(b, "", p.Name))
+			}
+		}
+	}
+	return result
+}
+
+// retryPolicy переводит политику повторов в конфигурацию балансировщика.
+// Неидемпотентные запросы по умолчанию не повторяются.
+func retryPolicy(r config.Retry) loadBalancer.RetryPolicy {
+	idempotentOnly := true
+	if r.IdempotentOnly != nil {
+		idempotentOnly = *r.IdempotentOnly
+	}
+	return loadBalancer.RetryPolicy{
+		MaxAttempts:    r.MaxAttempts,
+		RetryOnStatus:  r.RetryOnStatus,
+		RetryOnErrors:  r.RetryOnErrors,
+		IdempotentOnly: idempotentOnly,
+		BackoffBase:    r.BackoffBase,
+		BackoffMax:     r.BackoffMax,
+		Jitter:         r.Jitter,
+		Budget: loadBalancer.RetryBudget{
+			Ratio:               r.Budget.Ratio,
+			MinRetriesPerSecond: r.Budget.MinRetriesPerSecond,
+			Window:              r.Budget.Window,
+		},
+	}
+}
+
+// backendModel переводит бэкенд из конфигурации в модель.
+// group и pool - имена группы приоритета и пула, к которым относится бэкенд.
+func backendModel(b config.Backend, group, pool string) models.Backend {
+	var check models.HealthCheck
+	if b.HealthCheck != nil {
+		check = models.HealthCheck{
+			Type:           b.HealthCheck.Type,
+			Method:         b.HealthCheck.Method,
+			ExpectedStatus: b.HealthCheck.ExpectedStatus,
+			BodyContains:   b.HealthCheck.BodyContains,
+			BodyRegex:      b.HealthCheck.BodyRegex,
+			Headers:        b.HealthCheck.Headers,
+			Host:           b.HealthCheck.Host,
+			Timeout:        b.HealthCheck.Timeout,
+			Rise:           b.HealthCheck.Rise,
+			Fall:           b.HealthCheck.Fall,
+			Service:        b.HealthCheck.Service,
+			Command:        b.HealthCheck.Command,
+		}
+	}
+	return models.Backend{
+		URL:      b.URL,
+		Health:   b.Health,
+		Weight:   b.Weight,
+		Group:    group,
+		Pool:     pool,
+		Protocol: b.Protocol,
+		TLS: models.TLSConfig{
+			CAFile:     b.TLS.CAFile,
+			CertFile:   b.TLS.CertFile,
+			KeyFile:    b.TLS.KeyFile,
+			ServerName: b.TLS.ServerName,
+		},
+		Check: check,
+	}
+}
@@ -0,0 +1,52 @@
+package app
+
+import (
+	"context"
+	"go.uber.org/zap"
+	"lb/internal/config"
+	"lb/internal/modules/certificates"
+	"net"
+	"net/http"
+)
+
+// configureTLS загружает сертификаты фронтового листенера, настраивает выбор по SNI,
+// версию и шифры TLS, а также запускает перечитывание сертификатов с диска до отмены ctx
+func configureTLS(ctx context.Context, server *http.Server, cfg config.FrontTLS, logger *zap.Logger) error {
+	pairs := make([]certificates.KeyPair, len(cfg.Certificates))
+	for i, cert := range cfg.Certificates {
+		pairs[i] = certificates.KeyPair{CertFile: cert.CertFile, KeyFile: cert.KeyFile}
+	}
+
+	store, err := certificates.NewStore(pairs, logger)
+	if err != nil {
+		return err
+	}
+	tlsConfig, err := certificates.NewServerTLSConfig(store, certificates.ServerOptions{
+		MinVersion:   cfg.MinVersion,
+		CipherSuites: cfg.CipherSuites,
+	})
+	if err != nil {
+		return err
+	}
+
+	server.TLSConfig = tlsConfig
+	go store.Watch(ctx, cfg.ReloadInterval)
+	logger.Info("TLS termination enabled", zap.Int("certificates", len(pairs)))
+	return nil
+}
+
+// httpsRedirectHandler перенаправляет HTTP-запросы на тот же хост по HTTPS.
+// tlsAddress - адрес HTTPS-листенера; нестандартный порт добавляется в URL редиректа.
+func httpsRedirectHandler(tlsAddress string) http.Handler {
+	_, port, _ := net.SplitHostPort(tlsAddress)
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		host := r.Host
+		if h, _, err := net.SplitHostPort(host); err == nil {
+			host = h
+		}
+		if port != "" && port != "443" {
+			host = net.JoinHostPort(host, port)
+		}
+		http.Redirect(w, r, "https://"+host+r.URL.RequestURI(), http.StatusMovedPermanently)
+	})
+}
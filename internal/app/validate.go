@@ -0,0 +1,96 @@
+package app
+
+import (
+	"fmt"
+	"lb/internal/config"
+	"lb/internal/modules/backends"
+	"lb/internal/modules/certificates"
+	"lb/internal/modules/healthchecker"
+	"lb/internal/modules/loadBalancer"
+)
+
+func init() {
+	config.RegisterValidator(validateRoutes)
+	config.RegisterValidator(validateTLS)
+}
+
+// routeChecks - проверки маршрута, которые выполняются уже при загрузке конфигурации,
+// чтобы ошибка в config.yaml не обнаружилась только при создании балансировщиков
+var routeChecks = []func(route loadBalancer.RouteConfig) error{
+	// Алгоритм должен быть зарегистрирован, а его опции - корректны
+	func(route loadBalancer.RouteConfig) error {
+		_, err := loadBalancer.NewStrategy(route)
+		return err
+	},
+	// Протокол и TLS-файлы бэкендов проверяются заранее, а не на первом запросе
+	func(route loadBalancer.RouteConfig) error {
+		for _, backend := range route.Backends {
+			if _, err := backends.NewTransport(backend); err != nil {
+				return err
+			}
+		}
+		return nil
+	},
+	// Режим Host и доверенные прокси заголовков
+	func(route loadBalancer.RouteConfig) error {
+		if err := route.Headers.Validate(); err != nil {
+			return fmt.Errorf("headers: %w", err)
+		}
+		return nil
+	},
+	// Политика повторов и ее бюджет
+	func(route loadBalancer.RouteConfig) error {
+		if err := route.Retry.Validate(); err != nil {
+			return fmt.Errorf("retry: %w", err)
+		}
+		return nil
+	},
+	// Задержка и пределы хеджирования
+	func(route loadBalancer.RouteConfig) error {
+		return route.Hedge.Validate()
+	},
+	// Пороги и интервалы автоматов размыкания
+	func(route loadBalancer.RouteConfig) error {
+		return route.CircuitBreaker.Validate()
+	},
+	// Пороги и время исключения пассивной проверки
+	func(route loadBalancer.RouteConfig) error {
+		return route.OutlierDetection.Validate()
+	},
+	// Критерии активных проверок здоровья бэкендов
+	func(route loadBalancer.RouteConfig) error {
+		for _, backend := range route.Backends {
+			if err := healthchecker.ValidateHealthCheck(backend.Check); err != nil {
+				return fmt.Errorf("backend %s: %w", backend.URL, err)
+			}
+		}
+		return nil
+	},
+}
+
+// validateRoutes прогоняет маршруты конфигурации через routeChecks
+func validateRoutes(cfg *config.Config) error {
+	for _, route := range routeConfigs(cfg.Routes) {
+		for _, check := range routeChecks {
+			if err := check(route); err != nil {
+				return fmt.Errorf("invalid route %q: %w", route.Path, err)
+			}
+		}
+	}
+	return nil
+}
+
+// validateTLS проверяет минимальную версию и наборы шифров TLS фронтового листенера
+func validateTLS(cfg *config.Config) error {
+	tls := cfg.LoadBalancer.TLS
+	if !tls.Enabled {
+		return nil
+	}
+	if _, err := certificates.ParseVersion(tls.MinVersion); err != nil {
+		return fmt.Errorf("loadbalancer tls: %w", err)
+	}
+	if _, err := certificates.ParseCipherSuites(tls.CipherSuites); err != nil {
+		return fmt.Errorf("loadbalancer tls: %w", err)
+	}
+	return nil
+}
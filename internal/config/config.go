@@ -6,6 +6,19 @@ import (
 	"time"
 )
 
+// Validator - дополнительная проверка загруженной конфигурации.
+// Проверки, которым нужны типы модулей (алгоритмы балансировки, транспорт и т.п.),
+// регистрирует приложение через RegisterValidator: так config не зависит от модулей.
+type Validator func(*Config) error
+
+var validators []Validator
+
+// RegisterValidator добавляет проверку, которую LoadConfig выполняет после встроенных.
+// Вызывается при инициализации пакета, до загрузки конфигурации.
+func RegisterValidator(validator Validator) {
+	validators = append(validators, validator)
+}
+
 // LoadConfig загружает конфигурацию из файла с использованием Viper
 // configFile - имя конфигурационного файла (без расширения)
 func LoadConfig(configFile string) (*Config, error) {
@@ -34,9 +47,134 @@ func LoadConfig(configFile string) (*Config, error) {
 	if config.HealthChecker.UnhealthyServerFrequency == 0 {
 		config.HealthChecker.UnhealthyServerFrequency = 10 * time.Second
 	}
+
+	applyHealthCheckDefaults(config.Routes)
+	if err := validateRoutes(config.Routes); err != nil {
+		return nil, err
+	}
+	if err := validateFrontTLS(config.LoadBalancer.TLS); err != nil {
+		return nil, err
+	}
+	if config.LoadBalancer.TLS.Enabled && config.LoadBalancer.TLS.ReloadInterval == 0 {
+		config.LoadBalancer.TLS.ReloadInterval = 30 * time.Second
+	}
+	for _, validate := range validators {
+		if err := validate(&config); err != nil {
+			return nil, err
+		}
+	}
 	return &config, nil
 }
 
+// applyHealthCheckDefaults подставляет критерии проверки маршрута бэкендам,
+// у которых не заданы собственные
+func applyHealthCheckDefaults(routes []Route) {
+	for i := range routes {
+		route := &routes[i]
+		apply := func(list []Backend) {
+			for j := range list {
+				if list[j].HealthCheck == nil {
+					list[j].HealthCheck = &route.HealthCheck
+				}
+			}
+		}
+		apply(route.Backends)
+		for _, g := range route.Groups {
+			apply(g.Backends)
+		}
+		for _, p := range route.Pools {
+			apply(p.Backends)
+		}
+	}
+}
+
+// validateFrontTLS проверяет настройки TLS фронтового листенера
+func validateFrontTLS(cfg FrontTLS) error {
+	if !cfg.Enabled {
+		return nil
+	}
+	if len(cfg.Certificates) == 0 {
+		return fmt.Errorf("loadbalancer tls: at least one certificate is required")
+	}
+	for _, cert := range cfg.Certificates {
+		if cert.CertFile == "" || cert.KeyFile == "" {
+			return fmt.Errorf("loadbalancer tls: cert_file and key_file are required")
+		}
+	}
+	return nil
+}
+
+// validateRoutes проверяет, что группы и пулы маршрутов описаны однозначно.
+// Настройки, которые понимают только модули, проверяют зарегистрированные Validator.
+func validateRoutes(routes []Route) error {
+	for _, route := range routes {
+		if err := validateGroups(route); err != nil {
+			return fmt.Errorf("invalid route %q: %w", route.Path, err)
+		}
+		if err := validatePools(route); err != nil {
+			return fmt.Errorf("invalid route %q: %w", route.Path, err)
+		}
+		if route.Mirror.Percent < 0 || route.Mirror.Percent > 100 {
+			return fmt.Errorf("invalid route %q: mirror percent must be in [0, 100]", route.Path)
+		}
+	}
+	return nil
+}
+
 //func parseDuration(str string) (time.Duration, error) {
 //	return time.ParseDuration(str)
 //}
+
+// validateGroups проверяет, что маршрут задает либо backends, либо groups,
+// а имена групп непустые и уникальные
+func validateGroups(route Route) error {
+	if len(route.Groups) == 0 {
+		return nil
+	}
+	if len(route.Backends) > 0 {
+		return fmt.Errorf("backends and groups are mutually exclusive")
+	}
+
+	names := make(map[string]struct{}, len(route.Groups))
+	for _, group := range route.Groups {
+		if group.Name == "" {
+			return fmt.Errorf("backend group name is required")
+		}
+		if _, ok := names[group.Name]; ok {
+			return fmt.Errorf("duplicate backend group %q", group.Name)
+		}
+		names[group.Name] = struct{}{}
+	}
+	return nil
+}
+
+// validatePools проверяет, что пулы не смешаны с backends/groups,
+// их имена уникальны, а проценты в сумме дают 100
+func validatePools(route Route) error {
+	if len(route.Pools) == 0 {
+		return nil
+	}
+	if len(route.Backends) > 0 || len(route.Groups) > 0 {
+		return fmt.Errorf("pools are mutually exclusive with backends and groups")
+	}
+
+	names := make(map[string]struct{}, len(route.Pools))
+	total := 0
+	for _, pool := range route.Pools {
+		if pool.Name == "" {
+			return fmt.Errorf("backend pool name is required")
+		}
+		if _, ok := names[pool.Name]; ok {
+			return fmt.Errorf("duplicate backend pool %q", pool.Name)
+		}
+		if pool.Percent < 0 {
+			return fmt.Errorf("pool %q: percent must not be negative", pool.Name)
+		}
+		names[pool.Name] = struct{}{}
+		total += pool.Percent
+	}
+	if total != 100 {
+		return fmt.Errorf("pool percents must sum to 100, got %d", total)
+	}
+	return nil
+}
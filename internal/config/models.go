@@ -3,13 +3,148 @@ package config
 import "time"
 
 type Route struct {
-	Path     string
-	Backends []Backend `mapstructure:"backends"`
+	Path             string
+	Algorithm        string            `mapstructure:"algorithm"`
+	AlgorithmOptions map[string]string `mapstructure:"options"`
+	HashKey          string            `mapstructure:"hash_key"`
+	Sticky           Sticky            `mapstructure:"sticky"`
+	Groups           []BackendGroup    `mapstructure:"groups"`
+	SlowStart        time.Duration     `mapstructure:"slow_start"`
+	Pools            []BackendPool     `mapstructure:"pools"`
+	PoolOverride     PoolOverride      `mapstructure:"pool_override"`
+	Mirror           Mirror            `mapstructure:"mirror"`
+	BodyBuffering    BodyBuffering     `mapstructure:"body_buffering"`
+	Streaming        Streaming         `mapstructure:"streaming"`
+	Headers          ProxyHeaders      `mapstructure:"headers"`
+	Retry            Retry             `mapstructure:"retry"`
+	Hedge            Hedge             `mapstructure:"hedge"`
+	CircuitBreaker   CircuitBreaker    `mapstructure:"circuit_breaker"`
+	OutlierDetection OutlierDetection  `mapstructure:"outlier_detection"`
+	HealthCheck      HealthCheck       `mapstructure:"health_check"` // критерии проверки для бэкендов без своих
+	Backends         []Backend         `mapstructure:"backends"`
+}
+
+type BackendGroup struct {
+	Name       string    `mapstructure:"name"`
+	Priority   int       `mapstructure:"priority"`
+	MinHealthy int       `mapstructure:"min_healthy"`
+	Backends   []Backend `mapstructure:"backends"`
+}
+
+type Sticky struct {
+	Enabled    bool          `mapstructure:"enabled"`
+	CookieName string        `mapstructure:"cookie_name"`
+	TTL        time.Duration `mapstructure:"ttl"`
+	SigningKey string        `mapstructure:"signing_key"`
 }
 
 type Backend struct {
-	URL    string `mapstructure:"url"`
-	Health string `mapstructure:"health"`
+	URL         string       `mapstructure:"url"`
+	Health      string       `mapstructure:"health"`
+	Weight      int          `mapstructure:"weight"`
+	Protocol    string       `mapstructure:"protocol"`
+	TLS         BackendTLS   `mapstructure:"tls"`
+	HealthCheck *HealthCheck `mapstructure:"health_check"`
+}
+
+type HealthCheck struct {
+	Type           string            `mapstructure:"type"`
+	Method         string            `mapstructure:"method"`
+	ExpectedStatus []string          `mapstructure:"expected_status"`
+	BodyContains   string            `mapstructure:"body_contains"`
+	BodyRegex      string            `mapstructure:"body_regex"`
+	Headers        map[string]string `mapstructure:"headers"`
+	Host           string            `mapstructure:"host"`
+	Timeout        time.Duration     `mapstructure:"timeout"`
+	Rise           int               `mapstructure:"rise"`
+	Fall           int               `mapstructure:"fall"`
+	Service        string            `mapstructure:"service"`
+	Command        []string          `mapstructure:"command"`
+}
+
+type BackendTLS struct {
+	CAFile     string `mapstructure:"ca_file"`
+	CertFile   string `mapstructure:"cert_file"`
+	KeyFile    string `mapstructure:"key_file"`
+	ServerName string `mapstructure:"server_name"`
+}
+
+type BackendPool struct {
+	Name     string    `mapstructure:"name"`
+	Percent  int       `mapstructure:"percent"`
+	Backends []Backend `mapstructure:"backends"`
+}
+
+type PoolOverride struct {
+	Header string `mapstructure:"header"`
+	Cookie string `mapstructure:"cookie"`
+}
+
+type Mirror struct {
+	URL       string `mapstructure:"url"`
+	Percent   int    `mapstructure:"percent"`
+	QueueSize int    `mapstructure:"queue_size"`
+}
+
+type BodyBuffering struct {
+	MaxMemory   int64  `mapstructure:"max_memory"`
+	SpoolToDisk bool   `mapstructure:"spool_to_disk"`
+	SpoolDir    string `mapstructure:"spool_dir"`
+}
+
+type Streaming struct {
+	FlushInterval time.Duration `mapstructure:"flush_interval"`
+	IdleTimeout   time.Duration `mapstructure:"idle_timeout"`
+}
+
+type ProxyHeaders struct {
+	Host           string   `mapstructure:"host"`
+	TrustedProxies []string `mapstructure:"trusted_proxies"`
+	Forwarded      bool     `mapstructure:"forwarded"`
+}
+
+type Retry struct {
+	MaxAttempts    int           `mapstructure:"max_attempts"`
+	RetryOnStatus  []int         `mapstructure:"retry_on_status"`
+	RetryOnErrors  []string      `mapstructure:"retry_on_errors"`
+	IdempotentOnly *bool         `mapstructure:"idempotent_only"` // по умолчанию true
+	BackoffBase    time.Duration `mapstructure:"backoff_base"`
+	BackoffMax     time.Duration `mapstructure:"backoff_max"`
+	Jitter         time.Duration `mapstructure:"jitter"`
+	Budget         RetryBudget   `mapstructure:"budget"`
+}
+
+type RetryBudget struct {
+	Ratio               float64       `mapstructure:"ratio"`
+	MinRetriesPerSecond int           `mapstructure:"min_retries_per_second"`
+	Window              time.Duration `mapstructure:"window"`
+}
+
+type Hedge struct {
+	Enabled     bool          `mapstructure:"enabled"`
+	Delay       time.Duration `mapstructure:"delay"`
+	Percentile  float64       `mapstructure:"percentile"`
+	MaxInFlight int           `mapstructure:"max_in_flight"`
+}
+
+type CircuitBreaker struct {
+	Enabled             bool          `mapstructure:"enabled"`
+	ConsecutiveFailures int           `mapstructure:"consecutive_failures"`
+	ErrorRate           float64       `mapstructure:"error_rate"`
+	MinRequests         int           `mapstructure:"min_requests"`
+	Window              time.Duration `mapstructure:"window"`
+	OpenTimeout         time.Duration `mapstructure:"open_timeout"`
+	HalfOpenRequests    int           `mapstructure:"half_open_requests"`
+}
+
+type OutlierDetection struct {
+	Enabled                  bool          `mapstructure:"enabled"`
+	Consecutive5xx           int           `mapstructure:"consecutive_5xx"`
+	ConsecutiveGatewayErrors int           `mapstructure:"consecutive_gateway_errors"`
+	LatencyFactor            float64       `mapstructure:"latency_factor"`
+	BaseEjectionTime         time.Duration `mapstructure:"base_ejection_time"`
+	MaxEjectionTime          time.Duration `mapstructure:"max_ejection_time"`
+	MaxEjectionPercent       int           `mapstructure:"max_ejection_percent"`
 }
 
 type RateLimiter struct {
@@ -19,7 +154,22 @@ type RateLimiter struct {
 }
 
 type LoadBalancer struct {
-	Address string `mapstructure:"address" yaml:"address"`
+	Address         string   `mapstructure:"address" yaml:"address"`
+	TLS             FrontTLS `mapstructure:"tls" yaml:"tls"`
+	RedirectAddress string   `mapstructure:"redirect_address" yaml:"redirect_address"` // HTTP-листенер с редиректом на HTTPS
+}
+
+type FrontTLS struct {
+	Enabled        bool          `mapstructure:"enabled" yaml:"enabled"`
+	Certificates   []Certificate `mapstructure:"certificates" yaml:"certificates"`
+	MinVersion     string        `mapstructure:"min_version" yaml:"min_version"`
+	CipherSuites   []string      `mapstructure:"cipher_suites" yaml:"cipher_suites"`
+	ReloadInterval time.Duration `mapstructure:"reload_interval" yaml:"reload_interval"`
+}
+
+type Certificate struct {
+	CertFile string `mapstructure:"cert_file" yaml:"cert_file"`
+	KeyFile  string `mapstructure:"key_file" yaml:"key_file"`
 }
 
 type HealthChecker struct {
@@ -37,4 +187,10 @@ type Config struct {
 	RateLimiter   RateLimiter       `mapstructure:"rateLimiter" yaml:"RateLimiter"`
 	LoadBalancer  LoadBalancer      `mapstructure:"loadbalancer" yaml:"LoadBalancer"`
 	HealthChecker HealthCheckerTime `mapstructure:"healthchecker" yaml:"healthchecker"`
+	Admin         Admin             `mapstructure:"admin" yaml:"admin"`
+}
+
+// Admin - доступ к admin API
+type Admin struct {
+	Token string `mapstructure:"token" yaml:"token"` // Bearer-токен; без него admin API только читает
 }
@@ -0,0 +1,383 @@
+package routes
+
+import (
+	"context"
+	"crypto/subtle"
+	"encoding/json"
+	"fmt"
+	"go.uber.org/zap"
+	"lb/internal/modules/backends"
+	"lb/internal/modules/backends/models"
+	"lb/internal/modules/healthchecker"
+	"lb/internal/modules/loadBalancer"
+	"net/http"
+	"sort"
+	"strings"
+	"time"
+)
+
+// healthEventsKeepAlive - период комментариев-пингов в потоке событий,
+// чтобы прокси между клиентом и балансировщиком не закрывали тихое соединение
+const healthEventsKeepAlive = 15 * time.Second
+
+// adminAuth защищает admin API bearer-токеном. Если токен не задан, admin API
+// доступен только на чтение: запросы, меняющие состояние балансировщика, отклоняются.
+func adminAuth(token string, next http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if token == "" {
+			if r.Method != http.MethodGet && r.Method != http.MethodHead {
+				http.Error(w, "admin writes require admin.token", http.StatusForbidden)
+				return
+			}
+			next(w, r)
+			return
+		}
+		provided, ok := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer ")
+		if !ok || subtle.ConstantTimeCompare([]byte(provided), []byte(token)) != 1 {
+			w.Header().Set("WWW-Authenticate", `Bearer realm="admin"`)
+			http.Error(w, "Unauthorized", http.StatusUnauthorized)
+			return
+		}
+		next(w, r)
+	}
+}
+
+// routeTiers - состояние групп приоритета одного маршрута
+type routeTiers struct {
+	Path string `json:"path"`
+	*loadBalancer.TierStatus
+}
+
+// tiersHandler возвращает активную группу приоритета и число здоровых бэкендов
+// в каждой группе для всех маршрутов, где группы заданы
+func tiersHandler(lbMap map[string]*loadBalancer.LoadBalancerHandler, logger *zap.Logger) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodGet {
+			http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+
+		result := make([]routeTiers, 0, len(lbMap))
+		for _, path := range sortedPaths(lbMap) {
+			if status := lbMap[path].Tiers(); status != nil {
+				result = append(result, routeTiers{Path: path, TierStatus: status})
+			}
+		}
+		writeJSON(w, result, logger)
+	}
+}
+
+// routePools - распределение трафика между пулами одного маршрута
+type routePools struct {
+	Path  string                    `json:"path"`
+	Pools []loadBalancer.PoolStatus `json:"pools"`
+}
+
+// splitsHandler показывает (GET) и меняет (PUT) распределение трафика между пулами.
+// PUT /admin/splits?path=/api с телом {"stable": 80, "canary": 20}
+func splitsHandler(lbMap map[string]*loadBalancer.LoadBalancerHandler, logger *zap.Logger) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		switch r.Method {
+		case http.MethodGet:
+			result := make([]routePools, 0, len(lbMap))
+			for _, path := range sortedPaths(lbMap) {
+				if pools := lbMap[path].Pools(); pools != nil {
+					result = append(result, routePools{Path: path, Pools: pools})
+				}
+			}
+			writeJSON(w, result, logger)
+		case http.MethodPut:
+			path := r.URL.Query().Get("path")
+			handler, ok := lbMap[path]
+			if !ok {
+				http.Error(w, "unknown route path", http.StatusNotFound)
+				return
+			}
+			var percents map[string]int
+			if err := json.NewDecoder(r.Body).Decode(&percents); err != nil {
+				http.Error(w, err.Error(), http.StatusBadRequest)
+				return
+			}
+			if err := handler.SetPoolSplit(percents); err != nil {
+				logger.Warn("Rejected traffic split update", zap.String("path", path), zap.Error(err))
+				http.Error(w, err.Error(), http.StatusBadRequest)
+				return
+			}
+			writeJSON(w, routePools{Path: path, Pools: handler.Pools()}, logger)
+		default:
+			http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		}
+	}
+}
+
+// routeRetries - счетчики попыток одного маршрута
+type routeRetries struct {
+	Path string `json:"path"`
+	loadBalancer.RetryStats
+	Hedging *loadBalancer.HedgeStats `json:"hedging,omitempty"`
+}
+
+// retriesHandler возвращает счетчики запросов, попыток, повторов и хеджирования по маршрутам
+func retriesHandler(lbMap map[string]*loadBalancer.LoadBalancerHandler, logger *zap.Logger) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodGet {
+			http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+
+		result := make([]routeRetries, 0, len(lbMap))
+		for _, path := range sortedPaths(lbMap) {
+			result = append(result, routeRetries{
+				Path:       path,
+				RetryStats: lbMap[path].RetryStats(),
+				Hedging:    lbMap[path].HedgeStats(),
+			})
+		}
+		writeJSON(w, result, logger)
+	}
+}
+
+// routeBreakers - автоматы размыкания бэкендов одного маршрута
+type routeBreakers struct {
+	Path     string                       `json:"path"`
+	Backends []loadBalancer.BreakerStatus `json:"backends"`
+}
+
+// breakersHandler возвращает состояние автоматов размыкания для маршрутов, где они включены
+func breakersHandler(lbMap map[string]*loadBalancer.LoadBalancerHandler, logger *zap.Logger) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodGet {
+			http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+
+		result := make([]routeBreakers, 0, len(lbMap))
+		for _, path := range sortedPaths(lbMap) {
+			if breakers := lbMap[path].CircuitBreakers(); breakers != nil {
+				result = append(result, routeBreakers{Path: path, Backends: breakers})
+			}
+		}
+		writeJSON(w, result, logger)
+	}
+}
+
+// routeMirror - счетчики зеркалирования одного маршрута
+type routeMirror struct {
+	Path string `json:"path"`
+	loadBalancer.MirrorStats
+}
+
+// mirrorsHandler возвращает счетчики зеркалирования для маршрутов, где оно включено,
+// в том числе число копий, потерянных из-за переполнения очереди
+func mirrorsHandler(lbMap map[string]*loadBalancer.LoadBalancerHandler, logger *zap.Logger) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodGet {
+			http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+
+		result := make([]routeMirror, 0, len(lbMap))
+		for _, path := range sortedPaths(lbMap) {
+			if stats := lbMap[path].MirrorStats(); stats != nil {
+				result = append(result, routeMirror{Path: path, MirrorStats: *stats})
+			}
+		}
+		writeJSON(w, result, logger)
+	}
+}
+
+// backendHealth - состояние одного бэкенда маршрута
+type backendHealth struct {
+	BackendId            uint64    `json:"backend_id"`
+	URL                  string    `json:"url"`
+	Group                string    `json:"group,omitempty"`
+	Pool                 string    `json:"pool,omitempty"`
+	Healthy              bool      `json:"healthy"`
+	CheckHealthy         bool      `json:"check_healthy"`
+	Ejected              bool      `json:"ejected"`
+	LastCheck            time.Time `json:"last_check,omitzero"`
+	LastError            string    `json:"last_error,omitempty"`
+	ConsecutiveFailures  int       `json:"consecutive_failures"`
+	ConsecutiveSuccesses int       `json:"consecutive_successes"`
+	UpgradedConnections  int       `json:"upgraded_connections"` // Открытые WebSocket и другие Upgrade-туннели
+}
+
+// routeBackends - бэкенды одного маршрута
+type routeBackends struct {
+	Path     string          `json:"path"`
+	Backends []backendHealth `json:"backends"`
+}
+
+// backendsHandler возвращает все бэкенды маршрутов с итоговым состоянием в реестре
+// и результатами активных проверок: время и ошибка последней проверки, серии подряд
+func backendsHandler(lbMap map[string]*loadBalancer.LoadBalancerHandler, registry *backends.BackendRegistry,
+	hc *healthchecker.HealthChecker, logger *zap.Logger) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodGet {
+			http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+
+		result := make([]routeBackends, 0, len(lbMap))
+		for _, path := range sortedPaths(lbMap) {
+			route := routeBackends{Path: path, Backends: []backendHealth{}}
+			for _, backend := range lbMap[path].Backends() {
+				health := registry.Health(backend.Id)
+				status := backendHealth{
+					BackendId:           backend.Id,
+					URL:                 backend.URL,
+					Group:               backend.Group,
+					Pool:                backend.Pool,
+					Healthy:             health.Healthy,
+					CheckHealthy:        health.Checked,
+					Ejected:             health.Ejected,
+					UpgradedConnections: lbMap[path].UpgradedConnections(backend.Id),
+				}
+				if state, ok := hc.State(backend.Id); ok {
+					status.LastCheck = state.LastCheck
+					status.LastError = state.LastError
+					if state.LastResult {
+						status.ConsecutiveSuccesses = state.Consecutive
+					} else {
+						status.ConsecutiveFailures = state.Consecutive
+					}
+				}
+				route.Backends = append(route.Backends, status)
+			}
+			result = append(result, route)
+		}
+		writeJSON(w, result, logger)
+	}
+}
+
+// healthEvent - переход состояния бэкенда в потоке событий
+type healthEvent struct {
+	BackendId uint64    `json:"backend_id"`
+	URL       string    `json:"url"`
+	Route     string    `json:"route"`
+	Healthy   bool      `json:"healthy"`
+	Source    string    `json:"source"` // health_check или outlier
+	Time      time.Time `json:"time"`
+}
+
+// resyncEvent сообщает, что часть переходов потеряна и состояние нужно перечитать из /admin/backends
+type resyncEvent struct {
+	Dropped uint64    `json:"dropped"`
+	Time    time.Time `json:"time"`
+}
+
+// healthEventsHandler отдает переходы состояния бэкендов из BackendRegistry.UpdateHealth
+// потоком Server-Sent Events: событие health с JSON в data на каждый переход.
+// Если клиент не успевает забирать события и часть из них потеряна, после уже
+// полученных отправляется событие resync. Поток завершается при отмене shutdown.
+func healthEventsHandler(shutdown context.Context, lbMap map[string]*loadBalancer.LoadBalancerHandler,
+	registry *backends.BackendRegistry, logger *zap.Logger) http.HandlerFunc {
+	// Бэкенды маршрутов не меняются после запуска, поэтому индекс строится один раз
+	routesById := make(map[uint64]string)
+	urlsById := make(map[uint64]string)
+	for path, handler := range lbMap {
+		for _, backend := range handler.Backends() {
+			routesById[backend.Id] = path
+			urlsById[backend.Id] = backend.URL
+		}
+	}
+
+	return func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodGet {
+			http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+
+		watcher := registry.Watch()
+		defer watcher.Stop()
+		updates := watcher.Updates()
+
+		rc := http.NewResponseController(w)
+		w.Header().Set("Content-Type", "text/event-stream")
+		w.Header().Set("Cache-Control", "no-cache")
+		w.WriteHeader(http.StatusOK)
+		if err := rc.Flush(); err != nil {
+			logger.Error("Health event stream is not supported by the connection", zap.Error(err))
+			return
+		}
+
+		keepAlive := time.NewTicker(healthEventsKeepAlive)
+		defer keepAlive.Stop()
+		for {
+			var (
+				event string
+				data  interface{}
+			)
+			select {
+			case <-r.Context().Done():
+				return
+			case <-shutdown.Done():
+				return
+			case <-keepAlive.C:
+				if _, err := fmt.Fprint(w, ": keepalive\n\n"); err != nil {
+					return
+				}
+			case status := <-updates:
+				source := "health_check"
+				if status.Source == models.SourceOutlier {
+					source = status.Source
+				}
+				event, data = "health", healthEvent{
+					BackendId: status.Id,
+					URL:       urlsById[status.Id],
+					Route:     routesById[status.Id],
+					Healthy:   status.IsHealthy,
+					Source:    source,
+					Time:      time.Now(),
+				}
+			}
+
+			if event != "" && !writeEvent(w, event, data, logger) {
+				return
+			}
+			// Потерянные события случились после тех, что уже в буфере,
+			// поэтому resync отправляется, когда буфер разобран
+			if len(updates) == 0 {
+				if dropped := watcher.TakeDropped(); dropped > 0 {
+					if !writeEvent(w, "resync", resyncEvent{Dropped: dropped, Time: time.Now()}, logger) {
+						return
+					}
+				}
+			}
+			if err := rc.Flush(); err != nil {
+				return
+			}
+		}
+	}
+}
+
+// writeEvent пишет событие Server-Sent Events с JSON в data.
+// Возвращает false, если соединение с клиентом разорвано.
+func writeEvent(w http.ResponseWriter, event string, data interface{}, logger *zap.Logger) bool {
+	encoded, err := json.Marshal(data)
+	if err != nil {
+		logger.Error("Error encoding health event to JSON", zap.Error(err))
+		return true
+	}
+	_, err = fmt.Fprintf(w, "event: %s\ndata: %s\n\n", event, encoded)
+	return err == nil
+}
+
+// writeJSON кодирует ответ admin API в JSON
+func writeJSON(w http.ResponseWriter, v interface{}, logger *zap.Logger) {
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(v); err != nil {
+		logger.Error("Error encoding admin response to JSON", zap.Error(err))
+		http.Error(w, "Failed to encode response", http.StatusInternalServerError)
+	}
+}
+
+// sortedPaths возвращает пути маршрутов в стабильном порядке
+func sortedPaths(lbMap map[string]*loadBalancer.LoadBalancerHandler) []string {
+	paths := make([]string, 0, len(lbMap))
+	for path := range lbMap {
+		paths = append(paths, path)
+	}
+	sort.Strings(paths)
+	return paths
+}
@@ -11,13 +11,17 @@ var (
 )
 
 func NewBackend(url string, health string) *models.Backend {
-	idMutex.Lock()
-	idCounter++
-	id := idCounter
-	idMutex.Unlock()
 	return &models.Backend{
-		Id:     id,
+		Id:     NextBackendId(),
 		URL:    url,
 		Health: health,
 	}
 }
+
+// NextBackendId выдает уникальный идентификатор для нового бэкенда
+func NextBackendId() uint64 {
+	idMutex.Lock()
+	defer idMutex.Unlock()
+	idCounter++
+	return idCounter
+}
@@ -1,12 +1,53 @@
 package models
 
+import "time"
+
 type Backend struct {
 	Id     uint64
 	URL    string
 	Health string
+	Weight int    // Вес бэкенда для взвешенных алгоритмов (0 трактуется как 1)
+	Group  string // Группа приоритета внутри маршрута (пусто, если группы не заданы)
+	Pool   string // Пул для разделения трафика, например stable или canary
+
+	Protocol string    // Протокол соединения: http1, h2c (по умолчанию), h2, auto
+	TLS      TLSConfig // Настройки TLS для https-бэкендов
+
+	Check HealthCheck // Критерии активной проверки по пути Health
+}
+
+// HealthCheck описывает активную проверку бэкенда и условия, при которых он здоров
+type HealthCheck struct {
+	Type           string            // http (по умолчанию), tcp, grpc или exec
+	Method         string            // GET (по умолчанию) или HEAD
+	ExpectedStatus []string          // Коды или диапазоны вида "200-299" (по умолчанию 200)
+	BodyContains   string            // Подстрока, которая должна быть в теле ответа
+	BodyRegex      string            // Регулярное выражение для тела ответа
+	Headers        map[string]string // Дополнительные заголовки запроса
+	Host           string            // Заголовок Host вместо адреса бэкенда
+	Timeout        time.Duration     // Таймаут проверки (0 - таймаут общего клиента)
+	Rise           int               // Успешных проверок подряд до признания здоровым (по умолчанию 1)
+	Fall           int               // Неудачных проверок подряд до признания нездоровым (по умолчанию 1)
+	Service        string            // Имя сервиса для grpc.health.v1.Health/Check (пусто - сервер целиком)
+	Command        []string          // Команда и аргументы для exec; здоров при коде выхода 0
+}
+
+// TLSConfig описывает TLS-соединение с бэкендом
+type TLSConfig struct {
+	CAFile     string // PEM-бандл доверенных CA вместо системных
+	CertFile   string // Клиентский сертификат для mTLS
+	KeyFile    string // Ключ клиентского сертификата
+	ServerName string // Переопределение SNI и имени для проверки сертификата
 }
 
+// Источники статуса бэкенда
+const (
+	SourceHealthCheck = ""        // активная проверка health checker'а
+	SourceOutlier     = "outlier" // пассивная проверка по результатам живых запросов
+)
+
 type BackendStatus struct {
 	Id        uint64
 	IsHealthy bool
+	Source    string // Кто выставил статус: SourceHealthCheck или SourceOutlier
 }
@@ -4,18 +4,64 @@ import (
 	"lb/internal/modules/backends/models"
 	"log"
 	"sync"
+	"sync/atomic"
 )
 
 // healthUpdateChannel - канал для рассылки обновлений статуса бэкендов
 type healthUpdateChannel chan models.BackendStatus
 
+// watchBufferSize - буфер канала наблюдателя за всеми бэкендами
+const watchBufferSize = 64
+
+// Watcher - подписка на переходы состояния всех бэкендов.
+// События копятся в буфере; если наблюдатель не успевает их забирать,
+// новые события теряются, а их число накапливается в счетчике потерь.
+type Watcher struct {
+	updates  healthUpdateChannel
+	dropped  atomic.Uint64
+	once     sync.Once
+	registry *BackendRegistry
+}
+
+// Updates возвращает канал событий; канал закрывается после Stop
+func (w *Watcher) Updates() <-chan models.BackendStatus {
+	return w.updates
+}
+
+// TakeDropped возвращает число потерянных с прошлого вызова событий и обнуляет счетчик
+func (w *Watcher) TakeDropped() uint64 {
+	return w.dropped.Swap(0)
+}
+
+// Stop отписывает наблюдателя и закрывает канал событий
+func (w *Watcher) Stop() {
+	w.once.Do(func() {
+		w.registry.mu.Lock()
+		defer w.registry.mu.Unlock()
+		delete(w.registry.watchers, w)
+		close(w.updates)
+	})
+}
+
+// HealthStatus - состояние бэкенда в реестре
+type HealthStatus struct {
+	Healthy bool // Итоговое состояние: прошел активную проверку и не исключен
+	Checked bool // Результат активной проверки
+	Ejected bool // Исключен пассивной проверкой
+}
+
 // BackendRegistry реализует потокобезопасное хранилище бэкендов
-// с механизмом подписки на изменения их состояния
+// с механизмом подписки на изменения их состояния.
+// Бэкенд здоров, если его считает здоровым health checker и он не исключен
+// пассивной проверкой (outlier detection).
 type BackendRegistry struct {
 	mu          sync.RWMutex
 	backendId   map[uint64]models.Backend
 	backends    map[uint64]models.BackendStatus
+	checked     map[uint64]bool // результат активной проверки
+	ejected     map[uint64]bool // исключен пассивной проверкой
 	subscribers map[uint64][]healthUpdateChannel
+	watchers    map[*Watcher]struct{} // наблюдатели за переходами всех бэкендов
 }
 
 // NewBackendRegistry создает новый экземпляр реестра бэкендов
@@ -23,11 +69,18 @@ func NewBackendRegistry() *BackendRegistry {
 	return &BackendRegistry{
 		backendId:   make(map[uint64]models.Backend),
 		backends:    make(map[uint64]models.BackendStatus),
+		checked:     make(map[uint64]bool),
+		ejected:     make(map[uint64]bool),
 		subscribers: make(map[uint64][]healthUpdateChannel),
+		watchers:    make(map[*Watcher]struct{}),
 	}
 }
 
-// UpdateHealth обновляет статус бэкенда и уведомляет подписчиков
+// UpdateHealth обновляет статус бэкенда и уведомляет подписчиков, если итоговое
+// состояние изменилось. Статус от health checker'а и исключение пассивной проверкой
+// учитываются раздельно: пока бэкенд исключен, успешные активные проверки
+// не возвращают его в работу, а по окончании исключения он возвращается,
+// только если активная проверка считает его здоровым.
 // Возвращает ошибку если передан пустой статус
 func (r *BackendRegistry) UpdateHealth(status models.BackendStatus) error {
 	if status == (models.BackendStatus{}) {
@@ -36,6 +89,18 @@ func (r *BackendRegistry) UpdateHealth(status models.BackendStatus) error {
 	r.mu.Lock()
 	defer r.mu.Unlock()
 
+	if status.Source == models.SourceOutlier {
+		r.ejected[status.Id] = !status.IsHealthy
+	} else {
+		r.checked[status.Id] = status.IsHealthy
+	}
+
+	previous, known := r.backends[status.Id]
+	status.IsHealthy = r.checked[status.Id] && !r.ejected[status.Id]
+	if known && previous.IsHealthy == status.IsHealthy {
+		return nil
+	}
+
 	// Сохраняем новый статус
 	r.backends[status.Id] = status
 
@@ -45,9 +110,40 @@ func (r *BackendRegistry) UpdateHealth(status models.BackendStatus) error {
 			ch <- status
 		}
 	}
+	// Наблюдатели не должны задерживать балансировщики: при переполненном
+	// буфере событие для медленного наблюдателя теряется и учитывается в счетчике потерь
+	for w := range r.watchers {
+		select {
+		case w.updates <- status:
+		default:
+			w.dropped.Add(1)
+		}
+	}
 	return nil
 }
 
+// Watch подписывает на переходы состояния всех бэкендов.
+// Наблюдатель нужно остановить через Stop.
+func (r *BackendRegistry) Watch() *Watcher {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	w := &Watcher{updates: make(healthUpdateChannel, watchBufferSize), registry: r}
+	r.watchers[w] = struct{}{}
+	return w
+}
+
+// Health возвращает текущее состояние бэкенда
+func (r *BackendRegistry) Health(backendId uint64) HealthStatus {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	return HealthStatus{
+		Healthy: r.backends[backendId].IsHealthy,
+		Checked: r.checked[backendId],
+		Ejected: r.ejected[backendId],
+	}
+}
+
 // Subscribe добавляет подписку на обновления статуса бэкенда
 // Возвращает канал для получения обновлений
 func (r *BackendRegistry) Subscribe(backendId uint64) <-chan models.BackendStatus {
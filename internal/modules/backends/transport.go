@@ -0,0 +1,115 @@
+package backends
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"fmt"
+	"golang.org/x/net/http2"
+	"lb/internal/modules/backends/models"
+	"net"
+	"net/http"
+	"os"
+	"time"
+)
+
+// Протоколы соединения с бэкендом
+const (
+	ProtocolHTTP1 = "http1" // HTTP/1.1 (по http:// или https://)
+	ProtocolH2C   = "h2c"   // HTTP/2 без TLS (по умолчанию)
+	ProtocolH2    = "h2"    // HTTP/2 поверх TLS
+	ProtocolAuto  = "auto"  // HTTP/2 через ALPN для https://, иначе HTTP/1.1
+)
+
+// NewTransport создает транспорт для бэкенда с учетом его протокола и настроек TLS.
+// Сертификаты сервера проверяются всегда: по системным корням или по CA из TLS.CAFile.
+func NewTransport(backend models.Backend) (http.RoundTripper, error) {
+	tlsConfig, err := newTLSConfig(backend.TLS)
+	if err != nil {
+		return nil, fmt.Errorf("backend %s: %w", backend.URL, err)
+	}
+
+	dialer := &net.Dialer{Timeout: 5 * time.Second, KeepAlive: 30 * time.Second}
+
+	switch backend.Protocol {
+	case "", ProtocolH2C:
+		return &http2.Transport{
+			AllowHTTP: true,
+			// Для h2c "TLS"-соединение на самом деле обычное TCP
+			DialTLSContext: func(ctx context.Context, network, addr string, _ *tls.Config) (net.Conn, error) {
+				return dialer.DialContext(ctx, network, addr)
+			},
+		}, nil
+	case ProtocolH2:
+		return &http2.Transport{TLSClientConfig: tlsConfig}, nil
+	case ProtocolHTTP1, ProtocolAuto:
+		transport := &http.Transport{
+			DialContext:         dialer.DialContext,
+			TLSClientConfig:     tlsConfig,
+			MaxIdleConns:        100,
+			MaxIdleConnsPerHost: 20,
+			IdleConnTimeout:     90 * time.Second,
+			TLSHandshakeTimeout: 5 * time.Second,
+			ForceAttemptHTTP2:   backend.Protocol == ProtocolAuto,
+		}
+		if backend.Protocol == ProtocolHTTP1 {
+			// Пустая карта отключает переход на HTTP/2 через ALPN
+			transport.TLSNextProto = make(map[string]func(string, *tls.Conn) http.RoundTripper)
+		}
+		return transport, nil
+	default:
+		return nil, fmt.Errorf("backend %s: unknown protocol %q (expected http1, h2c, h2 or auto)", backend.URL, backend.Protocol)
+	}
+}
+
+// HasCustomTransport сообщает, задан ли для бэкенда протокол или TLS,
+// то есть нельзя ли обойтись общим HTTP-клиентом
+func HasCustomTransport(backend models.Backend) bool {
+	return backend.Protocol != "" || backend.TLS != (models.TLSConfig{})
+}
+
+// newTLSConfig собирает клиентскую TLS-конфигурацию: свой CA, клиентский сертификат для mTLS и SNI
+func newTLSConfig(cfg models.TLSConfig) (*tls.Config, error) {
+	tlsConfig := &tls.Config{
+		MinVersion: tls.VersionTLS12,
+		ServerName: cfg.ServerName,
+	}
+
+	if cfg.CAFile != "" {
+		pem, err := os.ReadFile(cfg.CAFile)
+		if err != nil {
+			return nil, fmt.Errorf("read CA bundle: %w", err)
+		}
+		pool := x509.NewCertPool()
+		if !pool.AppendCertsFromPEM(pem) {
+			return nil, fmt.Errorf("CA bundle %s contains no PEM certificates", cfg.CAFile)
+		}
+		tlsConfig.RootCAs = pool
+	}
+
+	if cfg.CertFile != "" || cfg.KeyFile != "" {
+		cert, err := tls.LoadX509KeyPair(cfg.CertFile, cfg.KeyFile)
+		if err != nil {
+			return nil, fmt.Errorf("load client certificate: %w", err)
+		}
+		tlsConfig.Certificates = []tls.Certificate{cert}
+	}
+	return tlsConfig, nil
+}
+
+// NewUpgradeTransport создает HTTP/1.1-транспорт для проксирования Upgrade-запросов
+// (WebSocket и т.п.): переключение протокола возможно только поверх HTTP/1.1
+func NewUpgradeTransport(backend models.Backend) (*http.Transport, error) {
+	tlsConfig, err := newTLSConfig(backend.TLS)
+	if err != nil {
+		return nil, fmt.Errorf("backend %s: %w", backend.URL, err)
+	}
+	dialer := &net.Dialer{Timeout: 5 * time.Second, KeepAlive: 30 * time.Second}
+	return &http.Transport{
+		DialContext:         dialer.DialContext,
+		TLSClientConfig:     tlsConfig,
+		TLSHandshakeTimeout: 5 * time.Second,
+		TLSNextProto:        make(map[string]func(string, *tls.Conn) http.RoundTripper),
+		DisableKeepAlives:   true,
+	}, nil
+}
@@ -0,0 +1,152 @@
+package certificates
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"go.uber.org/zap"
+	"os"
+	"strings"
+	"sync"
+	"time"
+)
+
+// KeyPair - пути к PEM-файлам сертификата и ключа
+type KeyPair struct {
+	CertFile string
+	KeyFile  string
+}
+
+// Store хранит сертификаты фронтового TLS-листенера и выбирает их по SNI.
+// Файлы периодически перечитываются, поэтому обновленный сертификат
+// подхватывается без перезапуска процесса.
+type Store struct {
+	pairs    []KeyPair
+	mu       sync.RWMutex
+	certs    []*tls.Certificate          // в порядке конфигурации, первый - по умолчанию
+	byName   map[string]*tls.Certificate // DNS-имя (в нижнем регистре) -> сертификат
+	modTimes map[string]time.Time        // время изменения файлов при последней загрузке
+	logger   *zap.Logger
+}
+
+// NewStore загружает все пары сертификат/ключ. Ошибка любой пары - ошибка создания.
+func NewStore(pairs []KeyPair, logger *zap.Logger) (*Store, error) {
+	if len(pairs) == 0 {
+		return nil, errors.New("at least one certificate is required")
+	}
+	s := &Store{pairs: pairs, logger: logger}
+	if err := s.Reload(); err != nil {
+		return nil, err
+	}
+	return s, nil
+}
+
+// Reload перечитывает все сертификаты с диска и атомарно подменяет набор.
+// При ошибке продолжает использоваться предыдущий набор.
+func (s *Store) Reload() error {
+	certs := make([]*tls.Certificate, 0, len(s.pairs))
+	byName := make(map[string]*tls.Certificate)
+	modTimes := make(map[string]time.Time)
+
+	for _, pair := range s.pairs {
+		cert, err := tls.LoadX509KeyPair(pair.CertFile, pair.KeyFile)
+		if err != nil {
+			return fmt.Errorf("load certificate %s: %w", pair.CertFile, err)
+		}
+		leaf, err := x509.ParseCertificate(cert.Certificate[0])
+		if err != nil {
+			return fmt.Errorf("parse certificate %s: %w", pair.CertFile, err)
+		}
+		cert.Leaf = leaf
+
+		certs = append(certs, &cert)
+		for _, name := range certNames(leaf) {
+			if _, exists := byName[name]; !exists {
+				byName[name] = &cert
+			}
+		}
+		for _, file := range []string{pair.CertFile, pair.KeyFile} {
+			if info, err := os.Stat(file); err == nil {
+				modTimes[file] = info.ModTime()
+			}
+		}
+	}
+
+	s.mu.Lock()
+	s.certs, s.byName, s.modTimes = certs, byName, modTimes
+	s.mu.Unlock()
+	return nil
+}
+
+// GetCertificate выбирает сертификат по SNI: точное имя, затем wildcard,
+// иначе - первый сертификат из конфигурации. Подходит для tls.Config.GetCertificate.
+func (s *Store) GetCertificate(hello *tls.ClientHelloInfo) (*tls.Certificate, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	name := strings.ToLower(strings.TrimSuffix(hello.ServerName, "."))
+	if cert, ok := s.byName[name]; ok {
+		return cert, nil
+	}
+	if idx := strings.IndexByte(name, '.'); idx > 0 {
+		if cert, ok := s.byName["*"+name[idx:]]; ok {
+			return cert, nil
+		}
+	}
+	return s.certs[0], nil
+}
+
+// Watch раз в interval проверяет время изменения файлов и перечитывает
+// сертификаты при изменениях, пока не отменен ctx
+func (s *Store) Watch(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			if !s.changed() {
+				continue
+			}
+			if err := s.Reload(); err != nil {
+				s.logger.Error("Failed to reload TLS certificates, keeping previous ones", zap.Error(err))
+				continue
+			}
+			s.logger.Info("TLS certificates reloaded", zap.Int("count", len(s.pairs)))
+		}
+	}
+}
+
+// changed сообщает, изменился ли хотя бы один файл с момента последней загрузки
+func (s *Store) changed() bool {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	for _, pair := range s.pairs {
+		for _, file := range []string{pair.CertFile, pair.KeyFile} {
+			info, err := os.Stat(file)
+			if err != nil {
+				continue
+			}
+			if !info.ModTime().Equal(s.modTimes[file]) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// certNames возвращает DNS-имена сертификата в нижнем регистре
+func certNames(leaf *x509.Certificate) []string {
+	names := make([]string, 0, len(leaf.DNSNames)+1)
+	for _, name := range leaf.DNSNames {
+		names = append(names, strings.ToLower(name))
+	}
+	if len(names) == 0 && leaf.Subject.CommonName != "" {
+		names = append(names, strings.ToLower(leaf.Subject.CommonName))
+	}
+	return names
+}
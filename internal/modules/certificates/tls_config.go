@@ -0,0 +1,79 @@
+package certificates
+
+import (
+	"crypto/tls"
+	"fmt"
+	"strings"
+)
+
+// ServerOptions - параметры TLS фронтового листенера
+type ServerOptions struct {
+	MinVersion   string   // "1.2" (по умолчанию) или "1.3"
+	CipherSuites []string // Имена безопасных наборов шифров Go (например, TLS_ECDHE_RSA_WITH_AES_128_GCM_SHA256); пусто - по умолчанию
+}
+
+// NewServerTLSConfig собирает серверную TLS-конфигурацию с выбором сертификата
+// по SNI из store и поддержкой HTTP/2 для клиентов через ALPN
+func NewServerTLSConfig(store *Store, opts ServerOptions) (*tls.Config, error) {
+	minVersion, err := ParseVersion(opts.MinVersion)
+	if err != nil {
+		return nil, err
+	}
+	ciphers, err := ParseCipherSuites(opts.CipherSuites)
+	if err != nil {
+		return nil, err
+	}
+
+	return &tls.Config{
+		GetCertificate: store.GetCertificate,
+		MinVersion:     minVersion,
+		CipherSuites:   ciphers,
+		NextProtos:     []string{"h2", "http/1.1"},
+	}, nil
+}
+
+// ParseVersion преобразует строку версии TLS в константу crypto/tls.
+// Устаревшие TLS 1.0 и 1.1 не принимаются.
+func ParseVersion(version string) (uint16, error) {
+	switch strings.TrimPrefix(strings.ToLower(version), "tls") {
+	case "1.0", "1.1":
+		return 0, fmt.Errorf("TLS version %q is insecure, use 1.2 or 1.3", version)
+	case "", "1.2":
+		return tls.VersionTLS12, nil
+	case "1.3":
+		return tls.VersionTLS13, nil
+	default:
+		return 0, fmt.Errorf("unknown TLS version %q", version)
+	}
+}
+
+// ParseCipherSuites переводит имена наборов шифров в их идентификаторы.
+// Наборы из tls.InsecureCipherSuites отклоняются.
+// Наборы TLS 1.3 не настраиваются и в списке игнорируются Go.
+func ParseCipherSuites(names []string) ([]uint16, error) {
+	if len(names) == 0 {
+		return nil, nil
+	}
+
+	known := make(map[string]uint16)
+	for _, suite := range tls.CipherSuites() {
+		known[suite.Name] = suite.ID
+	}
+	insecure := make(map[string]bool)
+	for _, suite := range tls.InsecureCipherSuites() {
+		insecure[suite.Name] = true
+	}
+
+	ids := make([]uint16, 0, len(names))
+	for _, name := range names {
+		if insecure[name] {
+			return nil, fmt.Errorf("cipher suite %q is insecure", name)
+		}
+		id, ok := known[name]
+		if !ok {
+			return nil, fmt.Errorf("unknown cipher suite %q", name)
+		}
+		ids = append(ids, id)
+	}
+	return ids, nil
+}
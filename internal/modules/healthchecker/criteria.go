@@ -0,0 +1,207 @@
+package healthchecker
+
+import (
+	"fmt"
+	"io"
+	"lb/internal/modules/backends/models"
+	"net/http"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// maxCheckBodySize - сколько байт тела ответа читается для проверки содержимого
+const maxCheckBodySize = 64 << 10
+
+// Типы активной проверки
+const (
+	CheckHTTP = "http" // запрос по пути Health (по умолчанию)
+	CheckTCP  = "tcp"  // только установка TCP-соединения
+	CheckGRPC = "grpc" // grpc.health.v1.Health/Check
+	CheckExec = "exec" // локальная команда, здоров при коде выхода 0
+)
+
+// statusRange - допустимый диапазон кодов ответа, включая границы
+type statusRange struct {
+	from, to int
+}
+
+// checkCriteria - подготовленные критерии проверки одного бэкенда
+type checkCriteria struct {
+	kind         string
+	method       string
+	statuses     []statusRange
+	bodyContains string
+	bodyRegex    *regexp.Regexp
+	headers      http.Header
+	host         string
+	rise         int
+	fall         int
+	service      string
+	command      []string
+}
+
+// defaultCriteria соответствует прежнему поведению: GET и ответ 200
+var defaultCriteria = &checkCriteria{
+	kind:     CheckHTTP,
+	method:   http.MethodGet,
+	statuses: []statusRange{{from: http.StatusOK, to: http.StatusOK}},
+	rise:     1,
+	fall:     1,
+}
+
+// ValidateHealthCheck проверяет тип, метод, коды ответа, регулярное выражение и пороги rise/fall проверки
+func ValidateHealthCheck(check models.HealthCheck) error {
+	_, err := compileCriteria(check)
+	return err
+}
+
+// compileCriteria разбирает настройки проверки
+func compileCriteria(check models.HealthCheck) (*checkCriteria, error) {
+	c := &checkCriteria{
+		kind:         strings.ToLower(check.Type),
+		method:       strings.ToUpper(check.Method),
+		bodyContains: check.BodyContains,
+		headers:      make(http.Header, len(check.Headers)),
+		host:         check.Host,
+		rise:         check.Rise,
+		fall:         check.Fall,
+		service:      check.Service,
+		command:      check.Command,
+	}
+
+	switch c.kind {
+	case "":
+		c.kind = CheckHTTP
+	case CheckHTTP, CheckTCP, CheckGRPC, CheckExec:
+	default:
+		return nil, fmt.Errorf("unsupported health check type %q (expected http, tcp, grpc or exec)", check.Type)
+	}
+	if c.kind != CheckHTTP && (check.Method != "" || len(check.ExpectedStatus) > 0 || check.BodyContains != "" ||
+		check.BodyRegex != "" || len(check.Headers) > 0 || check.Host != "") {
+		return nil, fmt.Errorf("method, expected status, body match, headers and host apply only to http health checks")
+	}
+	if c.kind != CheckGRPC && check.Service != "" {
+		return nil, fmt.Errorf("health check service applies only to grpc health checks")
+	}
+	if c.kind == CheckExec && len(check.Command) == 0 {
+		return nil, fmt.Errorf("exec health check requires a command")
+	}
+	if c.kind != CheckExec && len(check.Command) > 0 {
+		return nil, fmt.Errorf("health check command applies only to exec health checks")
+	}
+
+	switch c.method {
+	case "":
+		c.method = http.MethodGet
+	case http.MethodGet:
+	case http.MethodHead:
+		if check.BodyContains != "" || check.BodyRegex != "" {
+			return nil, fmt.Errorf("health check body match requires GET method")
+		}
+	default:
+		return nil, fmt.Errorf("unsupported health check method %q (expected GET or HEAD)", check.Method)
+	}
+
+	if len(check.ExpectedStatus) == 0 {
+		c.statuses = defaultCriteria.statuses
+	}
+	for _, value := range check.ExpectedStatus {
+		r, err := parseStatusRange(value)
+		if err != nil {
+			return nil, err
+		}
+		c.statuses = append(c.statuses, r)
+	}
+
+	if check.BodyRegex != "" {
+		re, err := regexp.Compile(check.BodyRegex)
+		if err != nil {
+			return nil, fmt.Errorf("invalid health check body regex: %w", err)
+		}
+		c.bodyRegex = re
+	}
+	if check.Timeout < 0 {
+		return nil, fmt.Errorf("health check timeout must not be negative")
+	}
+	if c.rise < 0 || c.fall < 0 {
+		return nil, fmt.Errorf("health check rise and fall must not be negative")
+	}
+	if c.rise == 0 {
+		c.rise = defaultCriteria.rise
+	}
+	if c.fall == 0 {
+		c.fall = defaultCriteria.fall
+	}
+
+	for name, value := range check.Headers {
+		c.headers.Set(name, value)
+	}
+	return c, nil
+}
+
+// parseStatusRange разбирает код ("204") или диапазон ("200-299")
+func parseStatusRange(value string) (statusRange, error) {
+	from, to, isRange := strings.Cut(strings.TrimSpace(value), "-")
+	lo, err := strconv.Atoi(strings.TrimSpace(from))
+	if err != nil {
+		return statusRange{}, fmt.Errorf("invalid expected status %q", value)
+	}
+	hi := lo
+	if isRange {
+		if hi, err = strconv.Atoi(strings.TrimSpace(to)); err != nil {
+			return statusRange{}, fmt.Errorf("invalid expected status %q", value)
+		}
+	}
+	if lo < 100 || hi > 599 || lo > hi {
+		return statusRange{}, fmt.Errorf("invalid expected status %q", value)
+	}
+	return statusRange{from: lo, to: hi}, nil
+}
+
+// newRequest создает запрос проверки бэкенда
+func (c *checkCriteria) newRequest(backend *models.Backend) (*http.Request, error) {
+	req, err := http.NewRequest(c.method, backend.URL+backend.Health, nil)
+	if err != nil {
+		return nil, err
+	}
+	for name, values := range c.headers {
+		req.Header[name] = values
+	}
+	if c.host != "" {
+		req.Host = c.host
+	}
+	return req, nil
+}
+
+// evaluate проверяет ответ и возвращает причину, по которой бэкенд не здоров
+func (c *checkCriteria) evaluate(resp *http.Response) error {
+	if !c.statusAllowed(resp.StatusCode) {
+		return fmt.Errorf("unexpected status %d", resp.StatusCode)
+	}
+	if c.bodyContains == "" && c.bodyRegex == nil {
+		return nil
+	}
+
+	body, err := io.ReadAll(io.LimitReader(resp.Body, maxCheckBodySize))
+	if err != nil {
+		return fmt.Errorf("failed to read health check body: %w", err)
+	}
+	if c.bodyContains != "" && !strings.Contains(string(body), c.bodyContains) {
+		return fmt.Errorf("body does not contain %q", c.bodyContains)
+	}
+	if c.bodyRegex != nil && !c.bodyRegex.Match(body) {
+		return fmt.Errorf("body does not match %q", c.bodyRegex.String())
+	}
+	return nil
+}
+
+// statusAllowed сообщает, входит ли код ответа в ожидаемые
+func (c *checkCriteria) statusAllowed(status int) bool {
+	for _, r := range c.statuses {
+		if status >= r.from && status <= r.to {
+			return true
+		}
+	}
+	return false
+}
@@ -1,7 +1,9 @@
 package healthchecker
 
 import (
+	"fmt"
 	"go.uber.org/zap"
+	"io"
 	"lb/internal/modules/backends"
 	"lb/internal/modules/backends/models"
 	"net/http"
@@ -9,6 +11,15 @@ import (
 	"time"
 )
 
+// CheckState - результаты активных проверок одного бэкенда
+type CheckState struct {
+	Healthy     bool      // Состояние с учетом порогов rise/fall
+	LastResult  bool      // Результат последней проверки
+	Consecutive int       // Сколько проверок подряд дали LastResult
+	LastCheck   time.Time // Время последней проверки
+	LastError   string    // Причина последней неудачной проверки (пусто после успешной)
+}
+
 // HealthChecker реализует систему мониторинга состояния бэкендов.
 // Использует пул воркеров для асинхронных проверок и поддерживает
 // разные интервалы для здоровых/нездоровых сервисов.
@@ -17,8 +28,11 @@ type HealthChecker struct {
 	healthyFrequency   time.Duration
 	unhealthyFrequency time.Duration
 	registry           *backends.BackendRegistry
-	healthySet         sync.Map
+	mu                 sync.Mutex
+	states             map[uint64]*CheckState // Id бэкенда -> результаты проверок
 	httpClient         *http.Client
+	backendClients     sync.Map // Id бэкенда -> *http.Client для бэкендов со своим протоколом/TLS, gRPC или таймаутом
+	criteria           sync.Map // Id бэкенда -> *checkCriteria
 	logger             *zap.Logger
 }
 
@@ -37,6 +51,7 @@ func NewHealthChecker(
 		healthyFrequency:   healthyFreq,
 		unhealthyFrequency: unhealthyFreq,
 		registry:           registry,
+		states:             make(map[uint64]*CheckState),
 		httpClient:         httpClient,
 		logger:             logger,
 	}
@@ -53,9 +68,24 @@ func (hc *HealthChecker) Start() {
 
 // AddBackend добавляет бэкенд в систему мониторинга.
 // Гарантирует thread-safe добавление через буферизованный канал.
-func (hc *HealthChecker) AddBackend(backend *models.Backend) {
+// Возвращает ошибку, если критерии проверки или транспорт бэкенда некорректны.
+func (hc *HealthChecker) AddBackend(backend *models.Backend) error {
+	criteria, err := compileCriteria(backend.Check)
+	if err != nil {
+		return fmt.Errorf("health check: %w", err)
+	}
+	client, err := hc.newClient(backend, criteria)
+	if err != nil {
+		return fmt.Errorf("health check: %w", err)
+	}
+	hc.criteria.Store(backend.Id, criteria)
+	if client != nil {
+		hc.backendClients.Store(backend.Id, client)
+	}
+
 	hc.logger.Info("Backend added to health checker", zap.String("url", backend.URL))
 	hc.serverChan <- backend
+	return nil
 }
 
 // worker - основной цикл обработки проверок для одного воркера.
@@ -67,24 +97,23 @@ func (hc *HealthChecker) worker(id int) {
 	}
 }
 
-// checkBackend выполняет HTTP-проверку состояния бэкенда.
-// Логика проверки может быть расширена для поддержки разных протоколов.
+// checkBackend выполняет проверку состояния бэкенда и планирует следующую.
+// Тип проверки (http, tcp, grpc, exec) определяется настройками бэкенда.
 func (hc *HealthChecker) checkBackend(backend *models.Backend) {
-	healthy := false
-
-	resp, err := hc.httpClient.Get(backend.URL + backend.Health)
-	if err == nil && resp.StatusCode == http.StatusOK {
-		healthy = true
+	err := hc.probe(backend)
+	if err == nil {
 		hc.logger.Debug("Backend is healthy", zap.String("url", backend.URL))
 	} else {
 		hc.logger.Debug("Backend is unhealthy", zap.String("url", backend.URL), zap.Error(err))
 	}
 
-	hc.updateStatus(backend, healthy)
+	state := hc.updateStatus(backend, err)
 
-	// Динамическое планирование следующей проверки
+	// Динамическое планирование следующей проверки: с интервалом для здоровых
+	// проверяется только бэкенд, который здоров и прошел последнюю проверку.
+	// Пока идет серия rise/fall, следующая проверка нужна раньше.
 	var nextCheck time.Duration
-	if healthy {
+	if state.Healthy && state.LastResult {
 		nextCheck = hc.healthyFrequency
 	} else {
 		nextCheck = hc.unhealthyFrequency
@@ -95,19 +124,145 @@ func (hc *HealthChecker) checkBackend(backend *models.Backend) {
 	})
 }
 
-// updateStatus атомарно обновляет состояние бэкенда в registry и кэше.
-// Оптимизирует запросы к registry, обновляя только при изменении статуса.
-func (hc *HealthChecker) updateStatus(backend *models.Backend, isHealthy bool) {
-	_, exists := hc.healthySet.Load(backend.Id)
-
-	// Обновляем только при изменении состояния
-	if isHealthy && !exists {
-		hc.healthySet.Store(backend.Id, true)
-		hc.registry.UpdateHealth(models.BackendStatus{Id: backend.Id, IsHealthy: true})
-		hc.logger.Info("Marked backend healthy", zap.Uint64("id", backend.Id))
-	} else if !isHealthy && exists {
-		hc.healthySet.Delete(backend.Id)
-		hc.registry.UpdateHealth(models.BackendStatus{Id: backend.Id, IsHealthy: false})
-		hc.logger.Info("Marked backend unhealthy", zap.Uint64("id", backend.Id))
+// probe выполняет проверку бэкенда в зависимости от ее типа
+func (hc *HealthChecker) probe(backend *models.Backend) error {
+	criteria := hc.criteriaFor(backend)
+	switch criteria.kind {
+	case CheckTCP:
+		return hc.probeTCP(backend)
+	case CheckGRPC:
+		return hc.probeGRPC(backend, criteria)
+	case CheckExec:
+		return hc.probeExec(backend, criteria)
+	default:
+		return hc.probeHTTP(backend, criteria)
+	}
+}
+
+// probeHTTP отправляет запрос проверки и сверяет ответ с критериями бэкенда.
+// Тело ответа дочитывается и закрывается, чтобы соединение вернулось в пул.
+func (hc *HealthChecker) probeHTTP(backend *models.Backend, criteria *checkCriteria) error {
+	req, err := criteria.newRequest(backend)
+	if err != nil {
+		return err
+	}
+
+	resp, err := hc.clientFor(backend).Do(req)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		io.Copy(io.Discard, io.LimitReader(resp.Body, maxCheckBodySize))
+		resp.Body.Close()
+	}()
+
+	return criteria.evaluate(resp)
+}
+
+// criteriaFor возвращает критерии проверки, подготовленные в AddBackend
+func (hc *HealthChecker) criteriaFor(backend *models.Backend) *checkCriteria {
+	criteria, _ := hc.criteria.Load(backend.Id)
+	return criteria.(*checkCriteria)
+}
+
+// updateStatus учитывает результат проверки и меняет состояние бэкенда в registry,
+// только когда набрано rise успешных или fall неудачных проверок подряд.
+// Так единичный сбой или случайный успех не переключают бэкенд туда и обратно.
+// Исключение - первая проверка бэкенда: при старте ждать rise проверок
+// незачем, и успешная первая проверка сразу вводит бэкенд в работу.
+// Возвращает состояние бэкенда после учета проверки.
+func (hc *HealthChecker) updateStatus(backend *models.Backend, checkErr error) CheckState {
+	criteria := hc.criteriaFor(backend)
+	passed := checkErr == nil
+
+	hc.mu.Lock()
+	state, ok := hc.states[backend.Id]
+	if !ok {
+		state = &CheckState{}
+		hc.states[backend.Id] = state
+	}
+	if ok && state.LastResult == passed {
+		state.Consecutive++
+	} else {
+		state.LastResult = passed
+		state.Consecutive = 1
+	}
+	state.LastCheck = time.Now()
+	state.LastError = ""
+	if !passed {
+		state.LastError = checkErr.Error()
+	}
+
+	changed := false
+	if passed && !state.Healthy && (!ok || state.Consecutive >= criteria.rise) {
+		state.Healthy = true
+		changed = true
+	} else if !passed && state.Healthy && state.Consecutive >= criteria.fall {
+		state.Healthy = false
+		changed = true
+	}
+	result := *state
+	hc.mu.Unlock()
+
+	if !changed {
+		return result
+	}
+	hc.registry.UpdateHealth(models.BackendStatus{Id: backend.Id, IsHealthy: passed})
+	if passed {
+		hc.logger.Info("Marked backend healthy", zap.Uint64("id", backend.Id), zap.Int("consecutive", result.Consecutive))
+	} else {
+		hc.logger.Info("Marked backend unhealthy", zap.Uint64("id", backend.Id), zap.Int("consecutive", result.Consecutive))
+	}
+	return result
+}
+
+// State возвращает результаты активных проверок бэкенда; false - бэкенд еще не проверялся
+func (hc *HealthChecker) State(backendId uint64) (CheckState, bool) {
+	hc.mu.Lock()
+	defer hc.mu.Unlock()
+	state, ok := hc.states[backendId]
+	if !ok {
+		return CheckState{}, false
+	}
+	return *state, true
+}
+
+// clientFor возвращает HTTP-клиент для проверки бэкенда: собственный, созданный
+// в AddBackend, или общий
+func (hc *HealthChecker) clientFor(backend *models.Backend) *http.Client {
+	if client, ok := hc.backendClients.Load(backend.Id); ok {
+		return client.(*http.Client)
+	}
+	return hc.httpClient
+}
+
+// newClient создает собственный HTTP-клиент проверки бэкенда или возвращает nil,
+// если подходит общий. Бэкенды со своим протоколом или TLS, а также gRPC-проверки
+// (им нужен HTTP/2) идут через собственный транспорт, а бэкенды со своим
+// таймаутом проверки - через клиент с этим таймаутом вместо общего.
+func (hc *HealthChecker) newClient(backend *models.Backend, criteria *checkCriteria) (*http.Client, error) {
+	custom := backends.HasCustomTransport(*backend) || criteria.kind == CheckGRPC
+	if !custom && backend.Check.Timeout == 0 {
+		return nil, nil
+	}
+
+	transport := hc.httpClient.Transport
+	if custom {
+		var err error
+		if transport, err = backends.NewTransport(*backend); err != nil {
+			return nil, err
+		}
+	}
+	return &http.Client{
+		Transport: transport,
+		Timeout:   hc.timeoutFor(backend),
+	}, nil
+}
+
+// timeoutFor возвращает таймаут проверки: свой таймаут бэкенда или таймаут общего клиента
+func (hc *HealthChecker) timeoutFor(backend *models.Backend) time.Duration {
+	if backend.Check.Timeout > 0 {
+		return backend.Check.Timeout
 	}
+	return hc.httpClient.Timeout
 }
@@ -0,0 +1,199 @@
+package healthchecker
+
+import (
+	"bytes"
+	"context"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"lb/internal/modules/backends/models"
+	"net"
+	"net/http"
+	"net/url"
+	"os"
+	"os/exec"
+	"strings"
+	"time"
+)
+
+const (
+	// defaultProbeTimeout ограничивает tcp- и exec-проверки, если таймаут не задан ни бэкендом, ни общим клиентом
+	defaultProbeTimeout = 5 * time.Second
+	// grpcHealthPath - метод стандартного протокола проверки gRPC
+	grpcHealthPath = "/grpc.health.v1.Health/Check"
+	// grpcServing - значение HealthCheckResponse.ServingStatus.SERVING
+	grpcServing = 1
+	// maxExecOutput - сколько байт вывода команды попадает в ошибку проверки
+	maxExecOutput = 256
+)
+
+// grpcStatusNames - имена значений HealthCheckResponse.ServingStatus для сообщений об ошибке
+var grpcStatusNames = map[uint64]string{0: "UNKNOWN", 1: "SERVING", 2: "NOT_SERVING", 3: "SERVICE_UNKNOWN"}
+
+// probeTCP проверяет, что бэкенд принимает TCP-соединения по адресу из URL
+func (hc *HealthChecker) probeTCP(backend *models.Backend) error {
+	addr, err := backendAddr(backend.URL)
+	if err != nil {
+		return err
+	}
+	conn, err := net.DialTimeout("tcp", addr, hc.probeTimeout(backend))
+	if err != nil {
+		return err
+	}
+	return conn.Close()
+}
+
+// probeGRPC вызывает grpc.health.v1.Health/Check по HTTP/2 протоколом бэкенда (h2c по умолчанию).
+// Бэкенд здоров, если вызов завершился со статусом OK и сервис отвечает SERVING.
+func (hc *HealthChecker) probeGRPC(backend *models.Backend, criteria *checkCriteria) error {
+	// HealthCheckRequest{service = 1}
+	var msg []byte
+	if criteria.service != "" {
+		msg = append(msg, 0x0a)
+		msg = binary.AppendUvarint(msg, uint64(len(criteria.service)))
+		msg = append(msg, criteria.service...)
+	}
+	frame := make([]byte, 5, 5+len(msg))
+	binary.BigEndian.PutUint32(frame[1:], uint32(len(msg)))
+	frame = append(frame, msg...)
+
+	req, err := http.NewRequest(http.MethodPost, strings.TrimSuffix(backend.URL, "/")+grpcHealthPath, bytes.NewReader(frame))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/grpc")
+	req.Header.Set("TE", "trailers")
+
+	resp, err := hc.clientFor(backend).Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("unexpected status %d", resp.StatusCode)
+	}
+
+	body, err := io.ReadAll(io.LimitReader(resp.Body, maxCheckBodySize))
+	if err != nil {
+		return fmt.Errorf("failed to read grpc health response: %w", err)
+	}
+	// Статус приходит в трейлерах, а при ответе без тела - в заголовках
+	status, message := resp.Trailer.Get("Grpc-Status"), resp.Trailer.Get("Grpc-Message")
+	if status == "" {
+		status, message = resp.Header.Get("Grpc-Status"), resp.Header.Get("Grpc-Message")
+	}
+	if status != "0" {
+		return fmt.Errorf("grpc status %q: %s", status, message)
+	}
+
+	serving, err := parseServingStatus(body)
+	if err != nil {
+		return err
+	}
+	if serving != grpcServing {
+		name, ok := grpcStatusNames[serving]
+		if !ok {
+			name = fmt.Sprint(serving)
+		}
+		return fmt.Errorf("grpc health status %s", name)
+	}
+	return nil
+}
+
+// parseServingStatus извлекает поле status (1) из сообщения HealthCheckResponse в gRPC-кадре
+func parseServingStatus(frame []byte) (uint64, error) {
+	if len(frame) < 5 {
+		return 0, errors.New("grpc health response is too short")
+	}
+	if frame[0] != 0 {
+		return 0, errors.New("compressed grpc health response is not supported")
+	}
+	size := binary.BigEndian.Uint32(frame[1:5])
+	if uint64(len(frame)-5) < uint64(size) {
+		return 0, errors.New("truncated grpc health response")
+	}
+	msg := frame[5 : 5+size]
+
+	var status uint64 // отсутствующее поле означает UNKNOWN
+	for len(msg) > 0 {
+		key, n := binary.Uvarint(msg)
+		if n <= 0 {
+			return 0, errors.New("malformed grpc health response")
+		}
+		msg = msg[n:]
+		switch key & 7 {
+		case 0: // varint
+			value, n := binary.Uvarint(msg)
+			if n <= 0 {
+				return 0, errors.New("malformed grpc health response")
+			}
+			msg = msg[n:]
+			if key>>3 == 1 {
+				status = value
+			}
+		case 2: // поле с длиной
+			length, n := binary.Uvarint(msg)
+			if n <= 0 || uint64(len(msg)-n) < length {
+				return 0, errors.New("malformed grpc health response")
+			}
+			msg = msg[n+int(length):]
+		default:
+			return 0, fmt.Errorf("unexpected wire type %d in grpc health response", key&7)
+		}
+	}
+	return status, nil
+}
+
+// probeExec запускает команду проверки; бэкенд здоров при коде выхода 0.
+// Адрес бэкенда передается команде в переменной окружения LB_BACKEND_URL.
+func (hc *HealthChecker) probeExec(backend *models.Backend, criteria *checkCriteria) error {
+	ctx, cancel := context.WithTimeout(context.Background(), hc.probeTimeout(backend))
+	defer cancel()
+
+	cmd := exec.CommandContext(ctx, criteria.command[0], criteria.command[1:]...)
+	cmd.Env = append(os.Environ(), "LB_BACKEND_URL="+backend.URL)
+	// Не ждем дочерние процессы, унаследовавшие вывод, после завершения команды
+	cmd.WaitDelay = time.Second
+	output, err := cmd.CombinedOutput()
+	if err == nil {
+		return nil
+	}
+	if ctx.Err() != nil {
+		return fmt.Errorf("health check command timed out: %w", ctx.Err())
+	}
+	if out := strings.TrimSpace(string(output)); out != "" {
+		if len(out) > maxExecOutput {
+			out = out[:maxExecOutput]
+		}
+		return fmt.Errorf("%w: %s", err, out)
+	}
+	return err
+}
+
+// probeTimeout возвращает таймаут tcp- и exec-проверок
+func (hc *HealthChecker) probeTimeout(backend *models.Backend) time.Duration {
+	if timeout := hc.timeoutFor(backend); timeout > 0 {
+		return timeout
+	}
+	return defaultProbeTimeout
+}
+
+// backendAddr возвращает host:port бэкенда; порт по умолчанию выбирается по схеме URL
+func backendAddr(rawURL string) (string, error) {
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return "", fmt.Errorf("invalid backend url: %w", err)
+	}
+	if u.Hostname() == "" {
+		return "", fmt.Errorf("backend url %q has no host", rawURL)
+	}
+	port := u.Port()
+	if port == "" {
+		port = "80"
+		if u.Scheme == "https" {
+			port = "443"
+		}
+	}
+	return net.JoinHostPort(u.Hostname(), port), nil
+}
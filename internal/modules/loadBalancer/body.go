@@ -0,0 +1,120 @@
+package loadBalancer
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"net/http"
+	"os"
+)
+
+// defaultMaxBufferedBody - размер тела, до которого оно целиком держится в памяти
+const defaultMaxBufferedBody = 64 << 10
+
+// BodyBufferingConfig задает, как обрабатывается тело запроса перед проксированием.
+// Тела до MaxMemory буферизуются и допускают повторные попытки; более крупные
+// либо сбрасываются во временный файл (SpoolToDisk), либо передаются потоком без повторов.
+type BodyBufferingConfig struct {
+	MaxMemory   int64  // Порог буферизации в памяти, байт (по умолчанию 64KB)
+	SpoolToDisk bool   // Сохранять крупные тела во временный файл ради повторных попыток
+	SpoolDir    string // Каталог для временных файлов (по умолчанию os.TempDir)
+}
+
+// proxyBody - тело запроса к бэкенду в одном из трех вариантов:
+// в памяти, во временном файле или одноразовым потоком от клиента
+type proxyBody struct {
+	buffered []byte    // тело целиком в памяти (nil для пустого тела)
+	spool    *os.File  // временный файл с телом
+	stream   io.Reader // потоковое тело, читается один раз
+	streamed bool      // потоковое тело уже отдано на отправку
+	size     int64     // длина тела, -1 если неизвестна
+}
+
+// prepareBody читает тело запроса ровно настолько, чтобы решить, как его отправлять.
+// Маленькие тела буферизуются, крупные уходят на диск или передаются потоком.
+func prepareBody(r *http.Request, cfg BodyBufferingConfig) (*proxyBody, error) {
+	if r.Body == nil || r.Body == http.NoBody || r.ContentLength == 0 {
+		return &proxyBody{size: 0}, nil
+	}
+
+	maxMemory := cfg.MaxMemory
+	if maxMemory <= 0 {
+		maxMemory = defaultMaxBufferedBody
+	}
+
+	// Тело заведомо больше порога - не тратим память на префикс
+	if r.ContentLength > maxMemory {
+		return largeBody(r.Body, r.ContentLength, cfg)
+	}
+
+	// Длина неизвестна или в пределах порога: читаем не более maxMemory+1 байт
+	prefix, err := io.ReadAll(io.LimitReader(r.Body, maxMemory+1))
+	if err != nil {
+		return nil, err
+	}
+	if int64(len(prefix)) <= maxMemory {
+		return &proxyBody{buffered: prefix, size: int64(len(prefix))}, nil
+	}
+	return largeBody(io.MultiReader(bytes.NewReader(prefix), r.Body), r.ContentLength, cfg)
+}
+
+// largeBody сбрасывает тело во временный файл или оставляет его потоком
+func largeBody(src io.Reader, size int64, cfg BodyBufferingConfig) (*proxyBody, error) {
+	if !cfg.SpoolToDisk {
+		return &proxyBody{stream: src, size: size}, nil
+	}
+
+	file, err := os.CreateTemp(cfg.SpoolDir, "lb-body-*")
+	if err != nil {
+		return nil, err
+	}
+	// Файл удаляется сразу: дескриптор остается валидным до Close
+	os.Remove(file.Name())
+
+	written, err := io.Copy(file, src)
+	if err != nil {
+		file.Close()
+		return nil, err
+	}
+	return &proxyBody{spool: file, size: written}, nil
+}
+
+// replayable сообщает, можно ли отправить тело повторно
+func (b *proxyBody) replayable() bool {
+	return b.stream == nil
+}
+
+// bytes возвращает тело, если оно целиком в памяти (или пустое)
+func (b *proxyBody) bytes() ([]byte, bool) {
+	if b.stream != nil || b.spool != nil {
+		return nil, false
+	}
+	return b.buffered, true
+}
+
+// reader возвращает тело для очередной попытки отправки.
+// Потоковое тело можно получить только один раз.
+func (b *proxyBody) reader() (io.ReadCloser, error) {
+	switch {
+	case b.spool != nil:
+		return io.NopCloser(io.NewSectionReader(b.spool, 0, b.size)), nil
+	case b.stream != nil:
+		if b.streamed {
+			return nil, errors.New("streamed request body was already sent")
+		}
+		b.streamed = true
+		return io.NopCloser(b.stream), nil
+	case b.size == 0:
+		return http.NoBody, nil
+	default:
+		return io.NopCloser(bytes.NewReader(b.buffered)), nil
+	}
+}
+
+// Close освобождает временный файл, если он использовался
+func (b *proxyBody) Close() error {
+	if b.spool != nil {
+		return b.spool.Close()
+	}
+	return nil
+}
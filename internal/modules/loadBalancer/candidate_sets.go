@@ -0,0 +1,33 @@
+package loadBalancer
+
+// maxCandidateSets - сколько наборов кандидатов стратегия помнит одновременно.
+// Наборы меняются не только при смене здоровья: пулы, автоматы, разогрев
+// и хеджирование передают стратегии подмножества бэкендов маршрута.
+const maxCandidateSets = 16
+
+// recentSets хранит отпечатки недавно использованных наборов кандидатов
+// в порядке использования и подсказывает, состояние какого набора пора удалить.
+// Не потокобезопасен: вызывается под мьютексом стратегии.
+type recentSets struct {
+	order []string // от давно использованного к последнему
+}
+
+// touch отмечает использование набора signature. Если наборов стало больше
+// maxCandidateSets, возвращает отпечаток самого давно использованного из них.
+func (s *recentSets) touch(signature string) (string, bool) {
+	for i, seen := range s.order {
+		if seen == signature {
+			copy(s.order[i:], s.order[i+1:])
+			s.order[len(s.order)-1] = signature
+			return "", false
+		}
+	}
+
+	s.order = append(s.order, signature)
+	if len(s.order) <= maxCandidateSets {
+		return "", false
+	}
+	evicted := s.order[0]
+	s.order = s.order[1:]
+	return evicted, true
+}
@@ -0,0 +1,282 @@
+package loadBalancer
+
+import (
+	"fmt"
+	"go.uber.org/zap"
+	"lb/internal/modules/backends/models"
+	"sort"
+	"sync"
+	"time"
+)
+
+// Состояния автомата размыкания
+const (
+	BreakerClosed   = "closed"    // запросы идут как обычно
+	BreakerOpen     = "open"      // бэкенд исключен из выбора
+	BreakerHalfOpen = "half_open" // пропускаются пробные запросы
+)
+
+const (
+	defaultBreakerConsecutiveFailures = 5
+	defaultBreakerMinRequests         = 20
+	defaultBreakerWindow              = 10 * time.Second
+	defaultBreakerOpenTimeout         = 30 * time.Second
+	defaultBreakerHalfOpenRequests    = 1
+)
+
+// CircuitBreakerConfig задает автомат размыкания для каждого бэкенда маршрута.
+// Бэкенд размыкается после ConsecutiveFailures ошибок подряд или при доле ошибок
+// не ниже ErrorRate в скользящем окне. Через OpenTimeout он получает HalfOpenRequests
+// пробных запросов: все успешны - замыкается, любая ошибка - снова размыкается.
+type CircuitBreakerConfig struct {
+	Enabled             bool
+	ConsecutiveFailures int           // Ошибок подряд до размыкания (по умолчанию 5, отрицательное - не учитывать)
+	ErrorRate           float64       // Доля ошибок в окне от 0 до 1 (0 - не учитывать)
+	MinRequests         int           // Минимум запросов в окне для оценки доли ошибок (по умолчанию 20)
+	Window              time.Duration // Скользящее окно доли ошибок (по умолчанию 10s)
+	OpenTimeout         time.Duration // Время в разомкнутом состоянии (по умолчанию 30s)
+	HalfOpenRequests    int           // Пробные запросы в полуоткрытом состоянии (по умолчанию 1)
+}
+
+// BreakerStatus - состояние автомата размыкания одного бэкенда
+type BreakerStatus struct {
+	BackendId           uint64    `json:"backend_id"`
+	URL                 string    `json:"url"`
+	State               string    `json:"state"`
+	ConsecutiveFailures int       `json:"consecutive_failures"`
+	Requests            int       `json:"window_requests"`
+	Failures            int       `json:"window_failures"`
+	OpenedAt            time.Time `json:"opened_at,omitzero"`
+}
+
+// Validate проверяет пороги и интервалы автомата размыкания
+func (c CircuitBreakerConfig) Validate() error {
+	if !c.Enabled {
+		return nil
+	}
+	if c.ErrorRate < 0 || c.ErrorRate > 1 {
+		return fmt.Errorf("circuit breaker error rate must be in [0, 1]")
+	}
+	if c.MinRequests < 0 || c.HalfOpenRequests < 0 {
+		return fmt.Errorf("circuit breaker request counts must not be negative")
+	}
+	if c.Window < 0 || c.OpenTimeout < 0 {
+		return fmt.Errorf("circuit breaker durations must not be negative")
+	}
+	if c.Window > 0 && c.Window < time.Second {
+		return fmt.Errorf("circuit breaker window must be at least 1s")
+	}
+	return nil
+}
+
+// breaker - автомат размыкания одного бэкенда
+type breaker struct {
+	url         string
+	state       string
+	consecutive int
+	window      *slidingWindow // requests - запросы, marked - ошибки
+	openedAt    time.Time
+	probes      int // пробные запросы в полуоткрытом состоянии: выданные
+	successes   int // и успешно завершенные
+}
+
+// circuitBreakers хранит автоматы размыкания бэкендов маршрута
+type circuitBreakers struct {
+	mu                  sync.Mutex
+	consecutiveFailures int
+	errorRate           float64
+	minRequests         int
+	window              time.Duration
+	openTimeout         time.Duration
+	halfOpenRequests    int
+	breakers            map[uint64]*breaker
+	logger              *zap.Logger
+}
+
+// newCircuitBreakers возвращает nil, если автоматы размыкания выключены
+func newCircuitBreakers(cfg CircuitBreakerConfig, logger *zap.Logger) *circuitBreakers {
+	if !cfg.Enabled {
+		return nil
+	}
+	cb := &circuitBreakers{
+		consecutiveFailures: cfg.ConsecutiveFailures,
+		errorRate:           cfg.ErrorRate,
+		minRequests:         cfg.MinRequests,
+		window:              cfg.Window,
+		openTimeout:         cfg.OpenTimeout,
+		halfOpenRequests:    cfg.HalfOpenRequests,
+		breakers:            make(map[uint64]*breaker),
+		logger:              logger,
+	}
+	if cb.consecutiveFailures == 0 {
+		cb.consecutiveFailures = defaultBreakerConsecutiveFailures
+	}
+	if cb.minRequests == 0 {
+		cb.minRequests = defaultBreakerMinRequests
+	}
+	if cb.window == 0 {
+		cb.window = defaultBreakerWindow
+	}
+	if cb.openTimeout == 0 {
+		cb.openTimeout = defaultBreakerOpenTimeout
+	}
+	if cb.halfOpenRequests == 0 {
+		cb.halfOpenRequests = defaultBreakerHalfOpenRequests
+	}
+	return cb
+}
+
+// get возвращает автомат бэкенда, создавая его при первом обращении. Вызывается под mu.
+func (cb *circuitBreakers) get(backend *models.Backend) *breaker {
+	b, ok := cb.breakers[backend.Id]
+	if !ok {
+		b = &breaker{url: backend.URL, state: BreakerClosed, window: newSlidingWindow(cb.window)}
+		cb.breakers[backend.Id] = b
+	}
+	return b
+}
+
+// available возвращает бэкенды, которым сейчас можно отправить запрос:
+// замкнутые и полуоткрытые со свободными пробными запросами.
+// Разомкнутый бэкенд переходит в полуоткрытое состояние по истечении OpenTimeout.
+// Пробный слот занимает только reserve: available лишь сужает выбор.
+func (cb *circuitBreakers) available(backends []*models.Backend) []*models.Backend {
+	cb.mu.Lock()
+	defer cb.mu.Unlock()
+
+	result := make([]*models.Backend, 0, len(backends))
+	for _, backend := range backends {
+		b := cb.get(backend)
+		if b.state == BreakerOpen && time.Since(b.openedAt) >= cb.openTimeout {
+			cb.transition(b, BreakerHalfOpen, "open timeout elapsed")
+		}
+		switch {
+		case b.state == BreakerClosed:
+			result = append(result, backend)
+		case b.state == BreakerHalfOpen && b.probes < cb.halfOpenRequests:
+			result = append(result, backend)
+		}
+	}
+	return result
+}
+
+// reserve проверяет, что выбранному бэкенду можно отправить запрос, и в полуоткрытом
+// состоянии сразу занимает пробный слот. Проверка и захват идут под одной блокировкой,
+// поэтому параллельные запросы не получат больше HalfOpenRequests пробных слотов.
+// Занятый слот освобождает record или release.
+func (cb *circuitBreakers) reserve(backend *models.Backend) bool {
+	cb.mu.Lock()
+	defer cb.mu.Unlock()
+
+	switch b := cb.get(backend); b.state {
+	case BreakerClosed:
+		return true
+	case BreakerHalfOpen:
+		if b.probes < cb.halfOpenRequests {
+			b.probes++
+			return true
+		}
+	}
+	return false
+}
+
+// release освобождает пробный слот запроса, не давшего результата:
+// отмененного или так и не отправленного
+func (cb *circuitBreakers) release(backend *models.Backend) {
+	cb.mu.Lock()
+	defer cb.mu.Unlock()
+	if b := cb.get(backend); b.state == BreakerHalfOpen && b.probes > 0 {
+		b.probes--
+	}
+}
+
+// record учитывает результат запроса и переключает состояние автомата
+func (cb *circuitBreakers) record(backend *models.Backend, failed bool) {
+	cb.mu.Lock()
+	defer cb.mu.Unlock()
+
+	b := cb.get(backend)
+	if failed {
+		b.window.add(time.Now().Unix(), 1, 1)
+		b.consecutive++
+	} else {
+		b.window.add(time.Now().Unix(), 1, 0)
+		b.consecutive = 0
+	}
+
+	switch b.state {
+	case BreakerHalfOpen:
+		if failed {
+			cb.transition(b, BreakerOpen, "probe request failed")
+			return
+		}
+		b.successes++
+		if b.successes >= cb.halfOpenRequests {
+			cb.transition(b, BreakerClosed, "probe requests succeeded")
+		}
+	case BreakerClosed:
+		if !failed {
+			return
+		}
+		if cb.consecutiveFailures > 0 && b.consecutive >= cb.consecutiveFailures {
+			cb.transition(b, BreakerOpen, "consecutive failures")
+			return
+		}
+		if cb.errorRate > 0 {
+			counts := b.window.sum(time.Now().Unix())
+			if counts.requests >= cb.minRequests && float64(counts.marked) >= cb.errorRate*float64(counts.requests) {
+				cb.transition(b, BreakerOpen, "error rate exceeded")
+			}
+		}
+	}
+}
+
+// transition переводит автомат в новое состояние и логирует переход. Вызывается под mu.
+func (cb *circuitBreakers) transition(b *breaker, state, reason string) {
+	from := b.state
+	b.state = state
+	b.probes = 0
+	b.successes = 0
+
+	fields := []zap.Field{
+		zap.String("backend", b.url),
+		zap.String("from", from),
+		zap.String("to", state),
+		zap.String("reason", reason),
+	}
+	switch state {
+	case BreakerOpen:
+		b.openedAt = time.Now()
+		cb.logger.Warn("Circuit breaker opened", append(fields, zap.Int("consecutive_failures", b.consecutive))...)
+	case BreakerClosed:
+		b.openedAt = time.Time{}
+		b.consecutive = 0
+		b.window.reset()
+		cb.logger.Info("Circuit breaker closed", fields...)
+	default:
+		cb.logger.Info("Circuit breaker half-open", fields...)
+	}
+}
+
+// status возвращает состояние автоматов, отсортированное по Id бэкенда
+func (cb *circuitBreakers) status() []BreakerStatus {
+	cb.mu.Lock()
+	defer cb.mu.Unlock()
+
+	now := time.Now().Unix()
+	result := make([]BreakerStatus, 0, len(cb.breakers))
+	for id, b := range cb.breakers {
+		counts := b.window.sum(now)
+		result = append(result, BreakerStatus{
+			BackendId:           id,
+			URL:                 b.url,
+			State:               b.state,
+			ConsecutiveFailures: b.consecutive,
+			Requests:            counts.requests,
+			Failures:            counts.marked,
+			OpenedAt:            b.openedAt,
+		})
+	}
+	sort.Slice(result, func(i, j int) bool { return result[i].BackendId < result[j].BackendId })
+	return result
+}
@@ -0,0 +1,181 @@
+package loadBalancer
+
+import (
+	"errors"
+	"fmt"
+	"hash/fnv"
+	modelsBackend "lb/internal/modules/backends/models"
+	"net"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// defaultVirtualNodes - число виртуальных узлов на единицу веса бэкенда
+const defaultVirtualNodes = 160
+
+// Источники ключа хэширования
+const (
+	hashKeyIP     = "ip"
+	hashKeyHeader = "header"
+	hashKeyCookie = "cookie"
+	hashKeyPath   = "path"
+)
+
+// ------------------CONSISTENT HASH ------------------
+// ConsistentHashAlgorithm реализует кольцо консистентного хэширования с виртуальными узлами.
+// Один и тот же ключ запроса всегда попадает на один бэкенд, а при выпадении
+// бэкенда из списка здоровых перераспределяется только ~1/N ключей.
+type ConsistentHashAlgorithm struct {
+	keySource    string // ip | header | cookie | path
+	keyName      string // имя заголовка или cookie
+	virtualNodes int
+	fallback     *RoundRobinAlgorithm
+
+	mu    sync.Mutex
+	rings map[string][]ringNode // кольца по отпечатку набора бэкендов
+	sets  recentSets            // порядок использования наборов
+}
+
+// ringNode - точка на кольце, принадлежащая бэкенду
+type ringNode struct {
+	hash    uint64
+	backend *modelsBackend.Backend
+}
+
+// NewConsistentHashStrategy создает стратегию консистентного хэширования.
+// keySpec - источник ключа: "ip", "path", "header:<имя>" или "cookie:<имя>"
+// virtualNodes - число виртуальных узлов на единицу веса (0 - значение по умолчанию)
+func NewConsistentHashStrategy(keySpec string, virtualNodes int) (*ConsistentHashAlgorithm, error) {
+	source, name, err := parseHashKey(keySpec)
+	if err != nil {
+		return nil, err
+	}
+	if virtualNodes <= 0 {
+		virtualNodes = defaultVirtualNodes
+	}
+	return &ConsistentHashAlgorithm{
+		keySource:    source,
+		keyName:      name,
+		virtualNodes: virtualNodes,
+		fallback:     NewRoundRobinStrategy(),
+		rings:        make(map[string][]ringNode),
+	}, nil
+}
+
+// GetNextBackend используется, когда запрос недоступен - ключа нет, поэтому выбор по кругу
+func (ch *ConsistentHashAlgorithm) GetNextBackend(backends []*modelsBackend.Backend) (*modelsBackend.Backend, error) {
+	return ch.fallback.GetNextBackend(backends)
+}
+
+// GetBackendForRequest вычисляет ключ запроса и возвращает первый бэкенд
+// на кольце по часовой стрелке от хэша ключа
+func (ch *ConsistentHashAlgorithm) GetBackendForRequest(r *http.Request, backends []*modelsBackend.Backend) (*modelsBackend.Backend, error) {
+	if len(backends) == 0 {
+		return nil, errors.New("no backends available")
+	}
+
+	ring := ch.getRing(backends)
+	hash := hashString(ch.requestKey(r))
+	idx := sort.Search(len(ring), func(i int) bool { return ring[i].hash >= hash })
+	if idx == len(ring) {
+		idx = 0
+	}
+	return ring[idx].backend, nil
+}
+
+// requestKey извлекает ключ хэширования из запроса.
+// Если заголовка или cookie нет, используется IP клиента.
+func (ch *ConsistentHashAlgorithm) requestKey(r *http.Request) string {
+	switch ch.keySource {
+	case hashKeyHeader:
+		if v := r.Header.Get(ch.keyName); v != "" {
+			return v
+		}
+	case hashKeyCookie:
+		if c, err := r.Cookie(ch.keyName); err == nil && c.Value != "" {
+			return c.Value
+		}
+	case hashKeyPath:
+		return r.URL.Path
+	}
+
+	ip, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		return r.RemoteAddr
+	}
+	return ip
+}
+
+// getRing возвращает кольцо для набора бэкендов. Кольца кэшируются по набору:
+// запросы приходят и с подмножествами бэкендов маршрута (пулы, автоматы, разогрев),
+// и перестраивать кольцо при каждом переключении между ними слишком дорого.
+func (ch *ConsistentHashAlgorithm) getRing(backends []*modelsBackend.Backend) []ringNode {
+	signature := backendsSignature(backends)
+
+	ch.mu.Lock()
+	defer ch.mu.Unlock()
+	if evicted, ok := ch.sets.touch(signature); ok {
+		delete(ch.rings, evicted)
+	}
+	if ring, ok := ch.rings[signature]; ok {
+		return ring
+	}
+
+	ring := make([]ringNode, 0, len(backends)*ch.virtualNodes)
+	for _, backend := range backends {
+		// Точки зависят только от URL бэкенда, поэтому не меняются при смене состава кольца
+		for i := 0; i < ch.virtualNodes*effectiveWeight(backend); i++ {
+			ring = append(ring, ringNode{
+				hash:    hashString(backend.URL + "#" + strconv.Itoa(i)),
+				backend: backend,
+			})
+		}
+	}
+	sort.Slice(ring, func(i, j int) bool { return ring[i].hash < ring[j].hash })
+	ch.rings[signature] = ring
+	return ring
+}
+
+// backendsSignature строит строковый отпечаток набора бэкендов по их Id
+func backendsSignature(backends []*modelsBackend.Backend) string {
+	ids := make([]uint64, len(backends))
+	for i, backend := range backends {
+		ids[i] = backend.Id
+	}
+	sort.Slice(ids, func(i, j int) bool { return ids[i] < ids[j] })
+
+	var sb strings.Builder
+	for _, id := range ids {
+		sb.WriteString(strconv.FormatUint(id, 10))
+		sb.WriteByte(',')
+	}
+	return sb.String()
+}
+
+// parseHashKey разбирает описание ключа хэширования вида "header:X-User-Id"
+func parseHashKey(spec string) (string, string, error) {
+	source, name, _ := strings.Cut(spec, ":")
+	switch source {
+	case "", hashKeyIP:
+		return hashKeyIP, "", nil
+	case hashKeyPath:
+		return hashKeyPath, "", nil
+	case hashKeyHeader, hashKeyCookie:
+		if name == "" {
+			return "", "", fmt.Errorf("hash key %q requires a name, e.g. %s:<name>", spec, source)
+		}
+		return source, name, nil
+	default:
+		return "", "", fmt.Errorf("unknown hash key source %q", source)
+	}
+}
+
+// hashString вычисляет 64-битный FNV-1a хэш строки
+func hashString(s string) uint64 {
+	h := fnv.New64a()
+	h.Write([]byte(s))
+	return h.Sum64()
+}
@@ -1,55 +1,137 @@
 package loadBalancer
 
 import (
-	"bytes"
 	"context"
-	"crypto/tls"
 	"errors"
+	"fmt"
 	"go.uber.org/zap"
-	"golang.org/x/net/http2"
 	"io"
 	"lb/internal/modules/backends"
 	"lb/internal/modules/backends/models"
-	"math/rand"
-	"net"
 	"net/http"
 	"strings"
 	"sync"
+	"sync/atomic"
 	"time"
 )
 
 // LoadBalancerHandler обрабатывает входящие HTTP-запросы, распределяя нагрузку между бэкендами.
 // Реализует механизм повторных попыток, кэширование соединений и буферизацию ответов.
 type LoadBalancerHandler struct {
-	lb         Loadbalancer
-	client     *http.Client
-	bufferPool *sync.Pool
-	mu         sync.RWMutex
-	logger     *zap.Logger
+	lb                *Loadbalancer
+	sticky            *stickySessions // nil, если привязка сессий выключена
+	split             *trafficSplit   // nil, если у маршрута нет пулов
+	mirror            *trafficMirror  // nil, если зеркалирование выключено
+	headers           *proxyHeaders
+	retry             *retryPolicy
+	hedge             *hedging         // nil, если хеджирование выключено
+	breakers          *circuitBreakers // nil, если автоматы размыкания выключены
+	outliers          *outlierDetector // nil, если пассивная проверка выключена
+	bodyBuffering     BodyBufferingConfig
+	streaming         StreamingConfig
+	clients           map[uint64]*http.Client    // HTTP-клиенты по Id бэкенда с учетом протокола и TLS
+	defaultClient     *http.Client               // h2c-клиент для бэкендов, не описанных в маршруте
+	upgradeTransports map[uint64]*http.Transport // HTTP/1.1-транспорты для WebSocket и других Upgrade
+	upgrades          *upgradeTracker
+	bufferPool        *sync.Pool
+	mu                sync.RWMutex
+	logger            *zap.Logger
+	backends          []models.Backend // все бэкенды маршрута, включая нездоровые
 }
 
 // NewLBHandler создает новый обработчик балансировщика нагрузки.
+// route - конфигурация маршрута (настройки привязки сессий и т.п.)
 // registry - реестр бэкендов для мониторинга их состояния
 // healthChannels - каналы для получения обновлений о состоянии бэкендов
-func NewLBHandler(registry *backends.BackendRegistry, healthChannels []<-chan models.BackendStatus, logger *zap.Logger) *LoadBalancerHandler {
+// algorithm - стратегия балансировки, выбранная для маршрута
+// Возвращает ошибку, если настройки маршрута или транспорта бэкенда некорректны.
+func NewLBHandler(route RouteConfig, registry *backends.BackendRegistry, healthChannels []<-chan models.BackendStatus, algorithm LoadBalancingStrategy, logger *zap.Logger) (*LoadBalancerHandler, error) {
+	headers, err := newProxyHeaders(route.Headers)
+	if err != nil {
+		return nil, fmt.Errorf("headers: %w", err)
+	}
+	retry, err := newRetryPolicy(route.Retry)
+	if err != nil {
+		return nil, fmt.Errorf("retry: %w", err)
+	}
+	if err := route.Hedge.Validate(); err != nil {
+		return nil, err
+	}
+	if err := route.CircuitBreaker.Validate(); err != nil {
+		return nil, err
+	}
+	if err := route.OutlierDetection.Validate(); err != nil {
+		return nil, err
+	}
+	clients, err := newBackendClients(route.Backends)
+	if err != nil {
+		return nil, err
+	}
+	defaultClient, err := newBackendClient(models.Backend{})
+	if err != nil {
+		return nil, err
+	}
+	upgradeTransports, err := newUpgradeTransports(route.Backends)
+	if err != nil {
+		return nil, err
+	}
+
 	return &LoadBalancerHandler{
-		lb:     *NewLoadBalancer(registry, healthChannels, logger),
-		logger: logger,
-		client: &http.Client{
-			Transport: &http2.Transport{
-				AllowHTTP: true, // Поддержка HTTP/2 без TLS (H2C)
-				DialTLS: func(network, addr string, cfg *tls.Config) (net.Conn, error) {
-					return net.Dial(network, addr)
-				},
-			},
-			Timeout: 10 * time.Second, // Глобальный таймаут для запросов к бэкенда
-		},
+		lb:                NewLoadBalancer(route, registry, healthChannels, algorithm, logger),
+		backends:          route.Backends,
+		sticky:            newStickySessions(route.Sticky, route.Path, logger),
+		split:             newTrafficSplit(route.Pools, route.PoolOverride),
+		mirror:            newTrafficMirror(route.Mirror, logger),
+		headers:           headers,
+		retry:             retry,
+		hedge:             newHedging(route.Hedge),
+		breakers:          newCircuitBreakers(route.CircuitBreaker, logger),
+		outliers:          newOutlierDetector(route.OutlierDetection, len(route.Backends), registry, logger),
+		bodyBuffering:     route.BodyBuffering,
+		streaming:         route.Streaming,
+		logger:            logger,
+		clients:           clients,
+		defaultClient:     defaultClient,
+		upgradeTransports: upgradeTransports,
+		upgrades:          newUpgradeTracker(),
 		bufferPool: &sync.Pool{
 			New: func() interface{} {
 				return make([]byte, 32<<10) // буффер 32KB
 			},
 		},
+	}, nil
+}
+
+// newBackendClients создает HTTP-клиент для каждого бэкенда маршрута
+func newBackendClients(backendsList []models.Backend) (map[uint64]*http.Client, error) {
+	clients := make(map[uint64]*http.Client, len(backendsList))
+	for _, backend := range backendsList {
+		client, err := newBackendClient(backend)
+		if err != nil {
+			return nil, err
+		}
+		clients[backend.Id] = client
 	}
+	return clients, nil
+}
+
+// newBackendClient создает HTTP-клиент с транспортом под протокол и TLS бэкенда
+func newBackendClient(backend models.Backend) (*http.Client, error) {
+	transport, err := backends.NewTransport(backend)
+	if err != nil {
+		return nil, err
+	}
+	// Таймауты задаются на каждую попытку в doWithTimeout: общий Timeout клиента
+	// обрывал бы долгие потоковые ответы
+	return &http.Client{Transport: transport}, nil
+}
+
+// clientFor возвращает HTTP-клиент бэкенда
+func (h *LoadBalancerHandler) clientFor(backend *models.Backend) *http.Client {
+	if client, ok := h.clients[backend.Id]; ok {
+		return client
+	}
+	return h.defaultClient
 }
 
 // ServeHTTP - основной обработчик HTTP-запросов, реализующий интерфейс http.Handler.
@@ -64,109 +146,490 @@ func (h *LoadBalancerHandler) ServeHTTP(w http.ResponseWriter, r *http.Request)
 		h.handleError(w, r, errors.New("no healthy backends available"), http.StatusServiceUnavailable, startTime)
 		return
 	}
+	// Бэкенды с разомкнутым автоматом не участвуют в выборе
+	backends = h.availableBackends(backends)
+	if len(backends) == 0 {
+		h.handleError(w, r, errors.New("all healthy backends have open circuit breakers"), http.StatusServiceUnavailable, startTime)
+		return
+	}
 
-	// Выбираем бэкенд по заданному алгоритму балансировки
-	backend, err := h.lb.Algorithm.GetNextBackend(backends)
-	if err != nil {
-		h.handleError(w, r, err, http.StatusServiceUnavailable, startTime)
+	// Пул, заданный заголовком или cookie переопределения, ограничивает выбор сразу,
+	// случайный пул - только если у клиента нет действующей привязки
+	var pool string
+	var forcedPool bool
+	if h.split != nil {
+		pool, forcedPool = h.split.choosePool(r)
+		if forcedPool {
+			backends = poolBackends(backends, pool)
+		}
+	}
+
+	// Клиент с действующей привязкой идет на свой бэкенд, пока тот здоров
+	var backend *models.Backend
+	if h.sticky != nil {
+		backend = h.sticky.lookup(r, backends)
+		// Пробный слот полуоткрытого бэкенда мог занять параллельный запрос
+		if backend != nil && !h.reserveBackend(backend) {
+			backend = nil
+		}
+	}
+
+	// Иначе выбираем бэкенд по заданному алгоритму балансировки
+	if backend == nil {
+		if h.split != nil && !forcedPool {
+			backends = poolBackends(backends, pool)
+		}
+		var err error
+		backend, err = h.selectAvailable(r, backends)
+		if err != nil {
+			h.handleError(w, r, err, http.StatusServiceUnavailable, startTime)
+			return
+		}
+		if h.sticky != nil {
+			h.sticky.setCookie(w, backend)
+		}
+	}
+
+	if h.split != nil {
+		h.split.record(backend.Pool)
+	}
+
+	// WebSocket и другие Upgrade-запросы проксируются туннелем.
+	// Их результат автомат размыкания не учитывает, поэтому пробный слот сразу освобождается.
+	if isUpgradeRequest(r) {
+		h.releaseBackend(backend)
+		h.proxyUpgrade(w, r, backend, startTime)
 		return
 	}
 
 	// Проксируем запрос к выбранному бэкенду
-	h.proxyRequest(ctx, w, r, backend, startTime)
+	h.proxyRequest(ctx, w, r, backend, backends, startTime)
+}
+
+// DrainUpgrades прекращает прием новых Upgrade-соединений, ждет завершения активных
+// до отмены ctx и принудительно закрывает оставшиеся. Возвращает число закрытых.
+func (h *LoadBalancerHandler) DrainUpgrades(ctx context.Context) int {
+	return h.upgrades.drain(ctx)
+}
+
+// UpgradedConnections возвращает число открытых WebSocket и других Upgrade-туннелей к бэкенду
+func (h *LoadBalancerHandler) UpgradedConnections(backendId uint64) int {
+	return h.upgrades.active(backendId)
+}
+
+// Pools возвращает проценты и счетчики запросов пулов или nil, если пулы не заданы
+func (h *LoadBalancerHandler) Pools() []PoolStatus {
+	if h.split == nil {
+		return nil
+	}
+	return h.split.status()
+}
+
+// SetPoolSplit меняет распределение трафика между пулами без перезапуска
+func (h *LoadBalancerHandler) SetPoolSplit(percents map[string]int) error {
+	if h.split == nil {
+		return errors.New("route has no backend pools")
+	}
+	if err := h.split.setPercents(percents); err != nil {
+		return err
+	}
+	h.logger.Info("Traffic split updated", zap.Any("percents", percents))
+	return nil
+}
+
+// RetryStats возвращает счетчики попыток маршрута
+func (h *LoadBalancerHandler) RetryStats() RetryStats {
+	return h.retry.stats()
+}
+
+// HedgeStats возвращает счетчики хеджирования или nil, если оно выключено
+func (h *LoadBalancerHandler) HedgeStats() *HedgeStats {
+	if h.hedge == nil {
+		return nil
+	}
+	stats := h.hedge.stats()
+	return &stats
+}
+
+// MirrorStats возвращает счетчики зеркалирования или nil, если оно выключено
+func (h *LoadBalancerHandler) MirrorStats() *MirrorStats {
+	if h.mirror == nil {
+		return nil
+	}
+	stats := h.mirror.stats()
+	return &stats
+}
+
+// CircuitBreakers возвращает состояние автоматов размыкания бэкендов или nil, если они выключены
+func (h *LoadBalancerHandler) CircuitBreakers() []BreakerStatus {
+	if h.breakers == nil {
+		return nil
+	}
+	return h.breakers.status()
+}
+
+// Backends возвращает все бэкенды маршрута независимо от их состояния
+func (h *LoadBalancerHandler) Backends() []models.Backend {
+	return h.backends
+}
+
+// HealthyBackends возвращает бэкенды, среди которых маршрут сейчас выбирает
+// (здоровые бэкенды активной группы приоритета)
+func (h *LoadBalancerHandler) HealthyBackends() []models.Backend {
+	active := h.lb.getHealthyBackends()
+	result := make([]models.Backend, len(active))
+	for i, backend := range active {
+		result[i] = *backend
+	}
+	return result
+}
+
+// Tiers возвращает состояние групп приоритета маршрута или nil, если группы не заданы
+func (h *LoadBalancerHandler) Tiers() *TierStatus {
+	return h.lb.tierStatus()
+}
+
+// availableBackends исключает бэкенды с разомкнутым автоматом
+func (h *LoadBalancerHandler) availableBackends(backends []*models.Backend) []*models.Backend {
+	if h.breakers == nil {
+		return backends
+	}
+	return h.breakers.available(backends)
+}
+
+// reserveBackend занимает пробный слот бэкенда, если его автомат полуоткрыт.
+// Возвращает false, если запрос бэкенду сейчас отправлять нельзя.
+func (h *LoadBalancerHandler) reserveBackend(backend *models.Backend) bool {
+	return h.breakers == nil || h.breakers.reserve(backend)
+}
+
+// releaseBackend освобождает пробный слот, занятый reserveBackend, если запрос не был отправлен
+func (h *LoadBalancerHandler) releaseBackend(backend *models.Backend) {
+	if h.breakers != nil {
+		h.breakers.release(backend)
+	}
+}
+
+// selectAvailable выбирает бэкенд и занимает его пробный слот.
+// Между availableBackends и выбором слот мог занять параллельный запрос:
+// тогда бэкенд исключается и выбор повторяется среди остальных.
+func (h *LoadBalancerHandler) selectAvailable(r *http.Request, backends []*models.Backend) (*models.Backend, error) {
+	for {
+		backend, err := h.selectBackend(r, backends)
+		if err != nil || h.reserveBackend(backend) {
+			return backend, err
+		}
+		backends = without(backends, backend)
+		if len(backends) == 0 {
+			return nil, errors.New("all healthy backends have open circuit breakers")
+		}
+	}
+}
+
+// selectBackend выбирает бэкенд стратегией маршрута с учетом разогрева.
+// Стратегия вызывается ровно один раз: взвешенные стратегии (WeightScaledStrategy)
+// сами уменьшают вес разогревающегося бэкенда, а для остальных он заранее
+// исключается из кандидатов с вероятностью, дополняющей его долю веса.
+func (h *LoadBalancerHandler) selectBackend(r *http.Request, backends []*models.Backend) (*models.Backend, error) {
+	return h.pickBackend(r, h.lb.slowStartCandidates(backends))
+}
+
+// pickBackend вызывает стратегию маршрута,
+// передавая ей запрос, если стратегия принимает решение по его содержимому
+func (h *LoadBalancerHandler) pickBackend(r *http.Request, backends []*models.Backend) (*models.Backend, error) {
+	if aware, ok := h.lb.Algorithm.(RequestAwareStrategy); ok {
+		return aware.GetBackendForRequest(r, backends)
+	}
+	return h.lb.Algorithm.GetNextBackend(backends)
 }
 
 // proxyRequest выполняет проксирование запроса к указанному бэкенду
 // с поддержкой повторных попыток и обработкой ошибок.
-func (h *LoadBalancerHandler) proxyRequest(ctx context.Context, w http.ResponseWriter, r *http.Request, backend *models.Backend, startTime time.Time) {
-	// Собираем целевой URL, сохраняя путь и параметры исходного запроса
-	targetURL := buildTargetURL(backend.URL, r.URL.Path, r.URL.RawQuery)
-
-	// Клонируем запрос, так как тело можно прочитать только один раз
-	req, body, err := cloneRequest(r, targetURL)
+// candidates - бэкенды, среди которых выбираются другие бэкенды для повторов.
+func (h *LoadBalancerHandler) proxyRequest(ctx context.Context, w http.ResponseWriter, r *http.Request, backend *models.Backend, candidates []*models.Backend, startTime time.Time) {
+	// Готовим тело: маленькое буферизуется, крупное уходит на диск или передается потоком
+	body, err := prepareBody(r, h.bodyBuffering)
 	if err != nil {
-		h.handleError(w, r, err, http.StatusInternalServerError, startTime)
+		h.releaseBackend(backend)
+		h.handleError(w, r, err, http.StatusBadRequest, startTime)
 		return
 	}
+	defer body.Close()
+
+	// Копия запроса уходит на зеркало асинхронно и не задерживает основной ответ.
+	// Зеркалируются только тела, уже находящиеся в памяти.
+	if h.mirror != nil {
+		if data, ok := body.bytes(); ok {
+			h.mirror.submit(r, data)
+		}
+	}
 
 	// Выполняем запрос с механизмом повторных попыток
-	resp, err := h.executeWithRetries(ctx, req, body, 3)
+	resp, final, err := h.executeWithRetries(ctx, r, body, backend, candidates)
+	// Стратегия считает запрос в обработке до полного копирования ответа.
+	// final равен nil, если запрос отменен между попытками: счетчик уже уменьшен.
+	if tracker, ok := h.lb.Algorithm.(RequestTracker); ok && final != nil {
+		defer tracker.RequestFinished(final)
+	}
 	if err != nil {
 		h.handleError(w, r, err, http.StatusBadGateway, startTime)
 		return
 	}
 	defer resp.Body.Close()
 
+	// Повтор ушел на другой бэкенд - переносим туда привязку сессии
+	if h.sticky != nil && final.Id != backend.Id {
+		h.sticky.setCookie(w, final)
+	}
+
 	// Копируем ответ бэкенда клиенту
 	h.copyResponse(w, resp)
 
 	h.logger.Debug("Request proxied successfully",
-		zap.String("backend", backend.URL),
+		zap.String("backend", final.URL),
 		zap.Int("status", resp.StatusCode),
 		zap.Duration("duration", time.Since(startTime)),
 	)
 }
 
-// executeWithRetries выполняет запрос с экспоненциальной задержкой между попытками.
-// Не повторяет запросы при клиентских ошибках (4xx), кроме 429 (Too Many Requests).
-func (h *LoadBalancerHandler) executeWithRetries(ctx context.Context, req *http.Request, body []byte, maxRetries int) (*http.Response, error) {
+// newBackendRequest создает запрос к бэкенду: целевой URL сохраняет путь и параметры
+// исходного запроса, заголовки проходят обработку прокси
+func (h *LoadBalancerHandler) newBackendRequest(r *http.Request, backend *models.Backend, body *proxyBody) (*http.Request, error) {
+	req, err := cloneRequest(r, buildTargetURL(backend.URL, r.URL.Path, r.URL.RawQuery), body)
+	if err != nil {
+		return nil, err
+	}
+	h.headers.apply(req, r)
+	return req, nil
+}
+
+// executeWithRetries выполняет запрос по политике повторов маршрута.
+// Каждая повторная попытка по возможности уходит на еще не опробованный бэкенд,
+// между попытками выдерживается экспоненциальная задержка.
+// Возвращает ответ и бэкенд последней попытки; RequestFinished для него вызывает вызывающий.
+// Если клиент отменил запрос во время задержки перед повтором, бэкенд равен nil.
+func (h *LoadBalancerHandler) executeWithRetries(ctx context.Context, r *http.Request, body *proxyBody, backend *models.Backend, candidates []*models.Backend) (*http.Response, *models.Backend, error) {
 	var resp *http.Response
 	var err error
 
-	for i := 0; i < maxRetries; i++ {
-		// Восстанавливаем тело запроса для каждой попытки
-		req.Body = io.NopCloser(bytes.NewReader(body))
+	tracker, _ := h.lb.Algorithm.(RequestTracker)
+	maxAttempts := h.retry.attemptsFor(r, body)
+	tried := make(map[uint64]struct{}, maxAttempts)
+	h.retry.recordRequest()
+
+	for attempt := 1; ; attempt++ {
+		tried[backend.Id] = struct{}{}
+		if tracker != nil {
+			tracker.RequestStarted(backend)
+		}
+		atomic.AddUint64(&h.retry.attempts, 1)
+
+		if h.hedge != nil && h.hedge.applies(r, body) {
+			resp, backend, err = h.hedgedAttempt(ctx, r, body, backend, candidates, tried)
+		} else {
+			resp, err = h.attempt(ctx, r, body, backend)
+		}
+
+		var retriable bool
+		if err != nil {
+			retriable = h.retry.retriableError(ctx, err)
+		} else {
+			retriable = h.retry.retriableStatus(resp.StatusCode)
+		}
+		if !retriable {
+			return resp, backend, err
+		}
+		if attempt >= maxAttempts {
+			break
+		}
+		// Все автоматы разомкнуты - повторять некуда, отдаем результат текущей попытки
+		next := h.nextRetryBackend(r, candidates, tried, backend)
+		if next == nil {
+			h.logger.Warn("Retry skipped: no backend with closed circuit breaker",
+				zap.String("backend", backend.URL),
+				zap.Int("attempt", attempt),
+			)
+			return resp, backend, err
+		}
+		// Бюджет исчерпан - отдаем результат текущей попытки, не нагружая пул повторами
+		if !h.retry.allowRetry() {
+			h.releaseBackend(next)
+			h.logger.Warn("Retry denied by retry budget",
+				zap.String("backend", backend.URL),
+				zap.Int("attempt", attempt),
+			)
+			return resp, backend, err
+		}
+
+		delay := h.retry.backoff(attempt)
+		fields := []zap.Field{
+			zap.String("backend", backend.URL),
+			zap.String("next_backend", next.URL),
+			zap.Int("attempt", attempt),
+			zap.Int("max_attempts", maxAttempts),
+			zap.Duration("backoff", delay),
+		}
+		if err != nil {
+			fields = append(fields, zap.String("error_class", classifyError(ctx, err)), zap.Error(err))
+		} else {
+			fields = append(fields, zap.Int("status", resp.StatusCode))
+		}
+		h.logger.Warn("Backend attempt failed, retrying", fields...)
 
-		resp, err = h.client.Do(req.WithContext(ctx))
-		if err == nil {
-			if resp.StatusCode >= 200 && resp.StatusCode < 300 {
-				return resp, nil
-			}
-			// 429 (Too Many Requests)
-			if resp.StatusCode >= 400 && resp.StatusCode < 500 && resp.StatusCode != 429 {
-				break
-			}
+		// Ответ неудачной попытки больше не нужен
+		if resp != nil {
+			resp.Body.Close()
+		}
+		if tracker != nil {
+			tracker.RequestFinished(backend)
 		}
 
-		// Экспоненциальная задержка с добавлением случайного jitter
-		if i < maxRetries-1 {
-			backoff := time.Duration(i)*time.Second + time.Duration(rand.Intn(100))*time.Millisecond
-			select {
-			case <-ctx.Done():
-				return nil, ctx.Err()
-			case <-time.After(backoff):
-			}
+		select {
+		case <-ctx.Done():
+			h.releaseBackend(next)
+			return nil, nil, ctx.Err()
+		case <-time.After(delay):
 		}
+		atomic.AddUint64(&h.retry.retries, 1)
+		backend = next
 	}
 
+	if maxAttempts > 1 {
+		atomic.AddUint64(&h.retry.exhausted, 1)
+	}
 	if err != nil {
 		h.logger.Error("Request to backend failed",
-			zap.String("url", req.URL.String()),
+			zap.String("url", backend.URL),
+			zap.Int("attempts", maxAttempts),
 			zap.Error(err),
 		)
-	} else if resp != nil {
+	} else {
 		h.logger.Error("Backend returned error status",
-			zap.String("url", req.URL.String()),
+			zap.String("url", backend.URL),
+			zap.Int("attempts", maxAttempts),
 			zap.Int("status", resp.StatusCode),
 		)
 	}
+	return resp, backend, err
+}
+
+// attempt выполняет одну попытку запроса к бэкенду и сообщает стратегии ее результат.
+// Пробный слот бэкенда уже занят при выборе (reserveBackend).
+func (h *LoadBalancerHandler) attempt(ctx context.Context, r *http.Request, body *proxyBody, backend *models.Backend) (*http.Response, error) {
+	req, err := h.newBackendRequest(r, backend, body)
+	if err != nil {
+		h.releaseBackend(backend)
+		return nil, err
+	}
+	// Восстанавливаем тело запроса для каждой попытки
+	req.Body, err = body.reader()
+	if err != nil {
+		h.releaseBackend(backend)
+		return nil, err
+	}
 
+	start := time.Now()
+	resp, err := doWithTimeout(ctx, h.clientFor(backend), req, defaultBackendTimeout)
+	h.observeAttempt(ctx, backend, resp, err, time.Since(start))
+	h.logger.Debug("Backend attempt finished",
+		zap.String("backend", backend.URL),
+		zap.Duration("duration", time.Since(start)),
+		zap.Bool("error", err != nil),
+	)
 	return resp, err
 }
 
+// observeAttempt сообщает результат попытки стратегии, автомату размыкания и пассивной проверке.
+// Отмененная попытка (проигравший хеджирующий запрос, ушедший клиент) о бэкенде ничего не говорит.
+func (h *LoadBalancerHandler) observeAttempt(ctx context.Context, backend *models.Backend, resp *http.Response, err error, latency time.Duration) {
+	if ctx.Err() != nil {
+		h.releaseBackend(backend)
+		return
+	}
+	status := 0
+	if err == nil {
+		status = resp.StatusCode
+	}
+	failed := err != nil || status >= http.StatusInternalServerError
+	if observer, ok := h.lb.Algorithm.(ResultObserver); ok {
+		observer.ObserveResult(backend, latency, failed)
+	}
+	if h.breakers != nil {
+		h.breakers.record(backend, failed)
+	}
+	if h.outliers != nil {
+		h.outliers.record(backend, status, latency)
+	}
+}
+
+// nextRetryBackend выбирает бэкенд для повторной попытки среди еще не опробованных
+// и занимает его пробный слот. Если опробованы все, повтор уходит на любой из кандидатов,
+// кроме текущего, если это возможно. Возвращает nil, если автоматы всех кандидатов разомкнуты.
+func (h *LoadBalancerHandler) nextRetryBackend(r *http.Request, candidates []*models.Backend, tried map[uint64]struct{}, current *models.Backend) *models.Backend {
+	rest := make([]*models.Backend, 0, len(candidates))
+	for _, candidate := range candidates {
+		if _, ok := tried[candidate.Id]; !ok {
+			rest = append(rest, candidate)
+		}
+	}
+	if len(rest) == 0 {
+		rest = without(candidates, current)
+	}
+	rest = h.availableBackends(rest)
+	if len(rest) == 0 {
+		rest = []*models.Backend{current}
+	}
+	next, err := h.selectAvailable(r, rest)
+	if err != nil {
+		return nil
+	}
+	return next
+}
+
 // copyResponse копирует ответ от бэкенда клиенту,
 // используя пул буферов для минимизации аллокаций памяти.
+// Потоковые ответы (SSE, без Content-Length) сбрасываются клиенту после каждой записи
+// и ограничиваются таймаутом простоя вместо общего дедлайна.
 func (h *LoadBalancerHandler) copyResponse(w http.ResponseWriter, resp *http.Response) {
+	removeHopHeaders(resp.Header)
+
+	// Добавляем, а не заменяем значения, чтобы не потерять уже выставленные заголовки (cookie привязки)
 	for k, v := range resp.Header {
-		w.Header()[k] = v
+		for _, value := range v {
+			w.Header().Add(k, value)
+		}
 	}
 
 	w.WriteHeader(resp.StatusCode)
 
+	var dst io.Writer = w
+	if isStreamingResponse(resp) {
+		if body, ok := resp.Body.(idleSwitcher); ok {
+			body.switchToIdle(h.streamIdleTimeout())
+		}
+		fw := newFlushWriter(w, 0)
+		defer fw.stop()
+		dst = fw
+		// Заголовки уходят сразу, не дожидаясь первого события
+		http.NewResponseController(w).Flush()
+	} else if h.streaming.FlushInterval > 0 {
+		fw := newFlushWriter(w, h.streaming.FlushInterval)
+		defer fw.stop()
+		dst = fw
+	}
+
 	// копируем body
 	buf := h.bufferPool.Get().([]byte)
 	defer h.bufferPool.Put(buf)
-	io.CopyBuffer(w, resp.Body, buf)
+	io.CopyBuffer(dst, resp.Body, buf)
+}
+
+// streamIdleTimeout возвращает таймаут простоя потоковых ответов маршрута
+func (h *LoadBalancerHandler) streamIdleTimeout() time.Duration {
+	if h.streaming.IdleTimeout > 0 {
+		return h.streaming.IdleTimeout
+	}
+	return defaultStreamIdleTimeout
 }
 
 // handleError обрабатывает ошибки, логируя их и возвращая клиенту соответствующий HTTP-статус.
@@ -194,20 +657,20 @@ func buildTargetURL(baseURL, path, query string) string {
 	return sb.String()
 }
 
-// cloneRequest создает копию исходного запроса для отправки к бэкенду,
-// включая клонирование тела запроса и заголовков.
-func cloneRequest(r *http.Request, targetURL string) (*http.Request, []byte, error) {
-	body, err := io.ReadAll(r.Body)
+// cloneRequest создает запрос к бэкенду с заголовками исходного запроса
+// (hop-by-hop и X-Forwarded-* обрабатываются затем в proxyHeaders.apply).
+// Тело подставляется перед каждой попыткой из body, длина передается заранее,
+// чтобы бэкенд получил Content-Length вместо chunked-кодирования.
+func cloneRequest(r *http.Request, targetURL string, body *proxyBody) (*http.Request, error) {
+	req, err := http.NewRequest(r.Method, targetURL, nil)
 	if err != nil {
-		return nil, nil, err
-	}
-	r.Body.Close()
-
-	req, err := http.NewRequest(r.Method, targetURL, bytes.NewReader(body))
-	if err != nil {
-		return nil, nil, err
+		return nil, err
 	}
 
 	req.Header = r.Header.Clone()
-	return req, body, nil
+	req.ContentLength = body.size
+	if body.replayable() {
+		req.GetBody = body.reader
+	}
+	return req, nil
 }
@@ -0,0 +1,193 @@
+package loadBalancer
+
+import (
+	"fmt"
+	"net"
+	"net/http"
+	"net/netip"
+	"strings"
+)
+
+// Режимы заголовка Host в запросе к бэкенду
+const (
+	HostRewrite  = "backend"  // Host бэкенда (по умолчанию)
+	HostPreserve = "preserve" // Host из запроса клиента
+)
+
+// ProxyHeadersConfig задает обработку заголовков обратного прокси для маршрута
+type ProxyHeadersConfig struct {
+	Host           string   // backend (по умолчанию) или preserve
+	TrustedProxies []string // IP/CIDR, чьим X-Forwarded-*/Forwarded можно доверять
+	Forwarded      bool     // Добавлять RFC 7239 Forwarded помимо X-Forwarded-*
+}
+
+// hopHeaders - заголовки уровня соединения (RFC 7230, 6.1), которые прокси не передает дальше
+var hopHeaders = []string{
+	"Connection",
+	"Proxy-Connection",
+	"Keep-Alive",
+	"Proxy-Authenticate",
+	"Proxy-Authorization",
+	"Te",
+	"Trailer",
+	"Transfer-Encoding",
+	"Upgrade",
+}
+
+// proxyHeaders применяет настройки заголовков маршрута к исходящим запросам
+type proxyHeaders struct {
+	preserveHost bool
+	forwarded    bool
+	trusted      []netip.Prefix
+}
+
+// ParseTrustedProxies разбирает список IP-адресов и CIDR доверенных прокси
+func ParseTrustedProxies(values []string) ([]netip.Prefix, error) {
+	prefixes := make([]netip.Prefix, 0, len(values))
+	for _, value := range values {
+		if strings.Contains(value, "/") {
+			prefix, err := netip.ParsePrefix(value)
+			if err != nil {
+				return nil, fmt.Errorf("invalid trusted proxy %q: %w", value, err)
+			}
+			prefixes = append(prefixes, prefix.Masked())
+			continue
+		}
+		addr, err := netip.ParseAddr(value)
+		if err != nil {
+			return nil, fmt.Errorf("invalid trusted proxy %q: %w", value, err)
+		}
+		prefixes = append(prefixes, netip.PrefixFrom(addr, addr.BitLen()))
+	}
+	return prefixes, nil
+}
+
+// Validate проверяет режим Host и список доверенных прокси
+func (c ProxyHeadersConfig) Validate() error {
+	_, err := newProxyHeaders(c)
+	return err
+}
+
+// newProxyHeaders проверяет конфигурацию заголовков маршрута
+func newProxyHeaders(cfg ProxyHeadersConfig) (*proxyHeaders, error) {
+	switch cfg.Host {
+	case "", HostRewrite, HostPreserve:
+	default:
+		return nil, fmt.Errorf("unknown host mode %q (expected %s or %s)", cfg.Host, HostRewrite, HostPreserve)
+	}
+	trusted, err := ParseTrustedProxies(cfg.TrustedProxies)
+	if err != nil {
+		return nil, err
+	}
+	return &proxyHeaders{
+		preserveHost: cfg.Host == HostPreserve,
+		forwarded:    cfg.Forwarded,
+		trusted:      trusted,
+	}, nil
+}
+
+// apply готовит заголовки запроса к бэкенду: убирает hop-by-hop, выставляет Host
+// и добавляет сведения о клиенте. Входящим X-Forwarded-*/Forwarded верим только
+// от доверенных прокси, иначе они заменяются.
+func (p *proxyHeaders) apply(out *http.Request, in *http.Request) {
+	removeHopHeaders(out.Header)
+	if p.preserveHost {
+		out.Host = in.Host
+	}
+
+	clientIP, _, err := net.SplitHostPort(in.RemoteAddr)
+	if err != nil {
+		clientIP = in.RemoteAddr
+	}
+	trusted := p.isTrusted(clientIP)
+	proto := "http"
+	if in.TLS != nil {
+		proto = "https"
+	}
+
+	if !trusted {
+		out.Header.Del("X-Forwarded-For")
+		out.Header.Del("X-Forwarded-Proto")
+		out.Header.Del("X-Forwarded-Host")
+		out.Header.Del("Forwarded")
+	}
+
+	if prior := out.Header.Values("X-Forwarded-For"); len(prior) > 0 {
+		out.Header.Set("X-Forwarded-For", strings.Join(prior, ", ")+", "+clientIP)
+	} else {
+		out.Header.Set("X-Forwarded-For", clientIP)
+	}
+	if out.Header.Get("X-Forwarded-Proto") == "" {
+		out.Header.Set("X-Forwarded-Proto", proto)
+	}
+	if out.Header.Get("X-Forwarded-Host") == "" {
+		out.Header.Set("X-Forwarded-Host", in.Host)
+	}
+
+	if p.forwarded {
+		element := fmt.Sprintf("for=%s;host=%s;proto=%s", forwardedNode(clientIP), quoteForwarded(in.Host), proto)
+		if prior := out.Header.Values("Forwarded"); len(prior) > 0 {
+			element = strings.Join(prior, ", ") + ", " + element
+		}
+		out.Header.Set("Forwarded", element)
+	}
+}
+
+// isTrusted проверяет, входит ли адрес непосредственного клиента в доверенные прокси
+func (p *proxyHeaders) isTrusted(ip string) bool {
+	if len(p.trusted) == 0 {
+		return false
+	}
+	addr, err := netip.ParseAddr(ip)
+	if err != nil {
+		return false
+	}
+	addr = addr.Unmap()
+	for _, prefix := range p.trusted {
+		if prefix.Contains(addr) {
+			return true
+		}
+	}
+	return false
+}
+
+// removeHopHeaders удаляет hop-by-hop заголовки, включая перечисленные в Connection.
+// "TE: trailers" сохраняется: без него не работают gRPC-бэкенды.
+func removeHopHeaders(h http.Header) {
+	for _, value := range h.Values("Connection") {
+		for _, name := range strings.Split(value, ",") {
+			if name = strings.TrimSpace(name); name != "" {
+				h.Del(name)
+			}
+		}
+	}
+
+	keepTrailers := false
+	for _, value := range h.Values("Te") {
+		if strings.EqualFold(strings.TrimSpace(value), "trailers") {
+			keepTrailers = true
+		}
+	}
+	for _, name := range hopHeaders {
+		h.Del(name)
+	}
+	if keepTrailers {
+		h.Set("Te", "trailers")
+	}
+}
+
+// forwardedNode форматирует адрес для параметра for= (IPv6 - в кавычках и скобках)
+func forwardedNode(ip string) string {
+	if strings.Contains(ip, ":") {
+		return `"[` + ip + `]"`
+	}
+	return ip
+}
+
+// quoteForwarded заключает значение в кавычки, если оно не является token (RFC 7230)
+func quoteForwarded(value string) string {
+	if strings.ContainsAny(value, ":[]\" ,;=") {
+		return `"` + strings.ReplaceAll(value, `"`, `\"`) + `"`
+	}
+	return value
+}
@@ -0,0 +1,304 @@
+package loadBalancer
+
+import (
+	"context"
+	"fmt"
+	"go.uber.org/zap"
+	"io"
+	"lb/internal/modules/backends/models"
+	"net/http"
+	"sort"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+const (
+	defaultHedgeMaxInFlight = 10
+	// hedgeLatencySamples - сколько последних задержек учитывается при расчете перцентиля
+	hedgeLatencySamples = 256
+	// hedgeMinSamples - минимум замеров, после которого задержка берется из перцентиля
+	hedgeMinSamples = 20
+)
+
+// HedgeConfig задает хеджирование: если бэкенд не ответил за Delay (или за время,
+// в которое укладывается Percentile последних ответов), тот же запрос уходит
+// на другой бэкенд, и клиент получает первый успешный ответ
+type HedgeConfig struct {
+	Enabled     bool
+	Delay       time.Duration // Задержка перед хеджирующим запросом (до набора статистики при Percentile)
+	Percentile  float64       // Перцентиль задержки ответов, например 95 (0 - всегда Delay)
+	MaxInFlight int           // Предел одновременных хеджирующих запросов маршрута (по умолчанию 10)
+}
+
+// HedgeStats - счетчики хеджирования маршрута
+type HedgeStats struct {
+	Hedged   uint64 `json:"hedged"`   // Отправлено хеджирующих запросов
+	Wins     uint64 `json:"wins"`     // Хеджирующий запрос ответил первым
+	Rejected uint64 `json:"rejected"` // Хеджирование пропущено из-за предела одновременных запросов
+}
+
+// Validate проверяет задержку, перцентиль и предел одновременных запросов
+func (c HedgeConfig) Validate() error {
+	if !c.Enabled {
+		return nil
+	}
+	if c.Delay <= 0 {
+		return fmt.Errorf("hedge delay must be positive")
+	}
+	if c.Percentile < 0 || c.Percentile >= 100 {
+		return fmt.Errorf("hedge percentile must be in [0, 100)")
+	}
+	if c.MaxInFlight < 0 {
+		return fmt.Errorf("hedge max in flight must not be negative")
+	}
+	return nil
+}
+
+// hedging хранит состояние хеджирования маршрута
+type hedging struct {
+	delay       time.Duration
+	percentile  float64
+	maxInFlight int64
+
+	mu        sync.Mutex
+	latencies []time.Duration // кольцевой буфер последних задержек
+	next      int
+
+	inFlight int64
+	hedged   uint64
+	wins     uint64
+	rejected uint64
+}
+
+// newHedging возвращает nil, если хеджирование выключено
+func newHedging(cfg HedgeConfig) *hedging {
+	if !cfg.Enabled {
+		return nil
+	}
+	maxInFlight := cfg.MaxInFlight
+	if maxInFlight == 0 {
+		maxInFlight = defaultHedgeMaxInFlight
+	}
+	return &hedging{
+		delay:       cfg.Delay,
+		percentile:  cfg.Percentile,
+		maxInFlight: int64(maxInFlight),
+		latencies:   make([]time.Duration, 0, hedgeLatencySamples),
+	}
+}
+
+// applies сообщает, можно ли хеджировать запрос: только идемпотентные чтения
+// с телом, которое можно отправить дважды
+func (hg *hedging) applies(r *http.Request, body *proxyBody) bool {
+	return (r.Method == http.MethodGet || r.Method == http.MethodHead) && body.replayable()
+}
+
+// currentDelay возвращает задержку перед хеджирующим запросом
+func (hg *hedging) currentDelay() time.Duration {
+	if hg.percentile == 0 {
+		return hg.delay
+	}
+	hg.mu.Lock()
+	if len(hg.latencies) < hedgeMinSamples {
+		hg.mu.Unlock()
+		return hg.delay
+	}
+	sorted := append([]time.Duration(nil), hg.latencies...)
+	hg.mu.Unlock()
+
+	sort.Slice(sorted, func(i, j int) bool { return sorted[i] < sorted[j] })
+	return sorted[int(float64(len(sorted)-1)*hg.percentile/100)]
+}
+
+// observe запоминает задержку ответа для расчета перцентиля
+func (hg *hedging) observe(latency time.Duration) {
+	if hg.percentile == 0 {
+		return
+	}
+	hg.mu.Lock()
+	defer hg.mu.Unlock()
+	if len(hg.latencies) < hedgeLatencySamples {
+		hg.latencies = append(hg.latencies, latency)
+		return
+	}
+	hg.latencies[hg.next] = latency
+	hg.next = (hg.next + 1) % hedgeLatencySamples
+}
+
+// acquire занимает место хеджирующего запроса, если предел не достигнут
+func (hg *hedging) acquire() bool {
+	if atomic.AddInt64(&hg.inFlight, 1) > hg.maxInFlight {
+		atomic.AddInt64(&hg.inFlight, -1)
+		atomic.AddUint64(&hg.rejected, 1)
+		return false
+	}
+	atomic.AddUint64(&hg.hedged, 1)
+	return true
+}
+
+// release освобождает место хеджирующего запроса.
+// Место занято, пока хеджирующий запрос не завершен полностью: до закрытия тела
+// выигравшего ответа или до завершения проигравшей попытки.
+func (hg *hedging) release() {
+	atomic.AddInt64(&hg.inFlight, -1)
+}
+
+// stats возвращает текущие значения счетчиков
+func (hg *hedging) stats() HedgeStats {
+	return HedgeStats{
+		Hedged:   atomic.LoadUint64(&hg.hedged),
+		Wins:     atomic.LoadUint64(&hg.wins),
+		Rejected: atomic.LoadUint64(&hg.rejected),
+	}
+}
+
+// hedgeResult - результат одной из параллельных попыток
+type hedgeResult struct {
+	resp    *http.Response
+	err     error
+	backend *models.Backend
+	done    func() // отменяет контекст попытки и освобождает место хеджирующего запроса
+	hedge   bool
+}
+
+// ok сообщает, можно ли отдать результат клиенту, не дожидаясь второй попытки
+func (res hedgeResult) ok() bool {
+	return res.err == nil && res.resp.StatusCode < http.StatusInternalServerError
+}
+
+// discard отменяет попытку и освобождает ее ответ
+func (res hedgeResult) discard() {
+	if res.resp != nil {
+		res.resp.Body.Close()
+	}
+	res.done()
+}
+
+// doneBody завершает выигравшую попытку после чтения ответа: для потоковых
+// ответов это происходит намного позже получения заголовков
+type doneBody struct {
+	io.ReadCloser
+	done func()
+}
+
+func (b *doneBody) Close() error {
+	err := b.ReadCloser.Close()
+	b.done()
+	return err
+}
+
+// switchToIdle передает переход на таймаут простоя телу попытки
+func (b *doneBody) switchToIdle(idle time.Duration) {
+	if body, ok := b.ReadCloser.(idleSwitcher); ok {
+		body.switchToIdle(idle)
+	}
+}
+
+// hedgedAttempt выполняет попытку с хеджированием: если бэкенд не ответил за задержку,
+// тот же запрос уходит на еще не опробованный бэкенд. Выигрывает первый успешный ответ,
+// проигравшая попытка отменяется. Возвращает ответ и бэкенд, который его дал;
+// RequestStarted для backend уже вызван, для хеджирующего бэкенда вызывается здесь.
+func (h *LoadBalancerHandler) hedgedAttempt(ctx context.Context, r *http.Request, body *proxyBody, backend *models.Backend, candidates []*models.Backend, tried map[uint64]struct{}) (*http.Response, *models.Backend, error) {
+	tracker, _ := h.lb.Algorithm.(RequestTracker)
+	results := make(chan hedgeResult, 2)
+	cancels := make(map[bool]context.CancelFunc, 2) // по признаку хеджирующей попытки
+	launch := func(target *models.Backend, hedge bool) {
+		attemptCtx, cancel := context.WithCancel(ctx)
+		cancels[hedge] = cancel
+		var once sync.Once
+		done := func() {
+			once.Do(func() {
+				cancel()
+				if hedge {
+					h.hedge.release()
+				}
+			})
+		}
+		go func() {
+			resp, err := h.attempt(attemptCtx, r, body, target)
+			results <- hedgeResult{resp: resp, err: err, backend: target, done: done, hedge: hedge}
+		}()
+	}
+
+	start := time.Now()
+	launch(backend, false)
+	pending := 1
+
+	timer := time.NewTimer(h.hedge.currentDelay())
+	defer timer.Stop()
+
+	var first hedgeResult
+	select {
+	case first = <-results:
+		pending--
+	case <-timer.C:
+		rest := make([]*models.Backend, 0, len(candidates))
+		for _, candidate := range candidates {
+			if _, ok := tried[candidate.Id]; !ok {
+				rest = append(rest, candidate)
+			}
+		}
+		rest = h.availableBackends(rest)
+		if len(rest) > 0 && h.hedge.acquire() {
+			if hedgeBackend, err := h.selectAvailable(r, rest); err == nil {
+				tried[hedgeBackend.Id] = struct{}{}
+				if tracker != nil {
+					tracker.RequestStarted(hedgeBackend)
+				}
+				h.logger.Debug("Sending hedged request",
+					zap.String("backend", backend.URL),
+					zap.String("hedge_backend", hedgeBackend.URL),
+				)
+				launch(hedgeBackend, true)
+				pending++
+			} else {
+				h.hedge.release()
+			}
+		}
+		first = <-results
+		pending--
+	}
+
+	// Первый результат неудачен, а вторая попытка еще идет - ждем ее
+	winner := first
+	if !first.ok() && pending > 0 {
+		second := <-results
+		pending--
+		if second.ok() {
+			winner = second
+			h.finishLoser(first, tracker)
+		} else {
+			h.finishLoser(second, tracker)
+		}
+	}
+
+	// Проигравшую попытку отменяем сразу, а ее завершение дожидаемся в фоне
+	if pending > 0 {
+		cancels[!winner.hedge]()
+		go func() {
+			loser := <-results
+			h.finishLoser(loser, tracker)
+		}()
+	}
+
+	if winner.err != nil {
+		winner.done()
+		return nil, winner.backend, winner.err
+	}
+	if winner.hedge {
+		atomic.AddUint64(&h.hedge.wins, 1)
+		h.logger.Debug("Hedged request won", zap.String("backend", winner.backend.URL))
+	}
+	h.hedge.observe(time.Since(start))
+	winner.resp.Body = &doneBody{ReadCloser: winner.resp.Body, done: winner.done}
+	return winner.resp, winner.backend, nil
+}
+
+// finishLoser отменяет проигравшую попытку и сообщает стратегии о ее завершении
+func (h *LoadBalancerHandler) finishLoser(res hedgeResult, tracker RequestTracker) {
+	res.discard()
+	if tracker != nil {
+		tracker.RequestFinished(res.backend)
+	}
+}
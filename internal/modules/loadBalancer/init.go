@@ -1,17 +1,35 @@
 package loadBalancer
 
 import (
+	"fmt"
 	"go.uber.org/zap"
 	"lb/internal/modules/backends"
 	models "lb/internal/modules/backends/models"
 	"lb/internal/modules/healthchecker"
+	"time"
 )
 
 // RouteConfig определяет конфигурацию маршрута для балансировщика нагрузки.
 // Содержит путь (endpoint) и список бэкендов, которые могут его обслуживать.
 type RouteConfig struct {
-	Path     string
-	Backends []models.Backend
+	Path             string
+	Algorithm        string            // Название алгоритма из реестра стратегий (по умолчанию round_robin)
+	AlgorithmOptions map[string]string // Опции, специфичные для алгоритма
+	HashKey          string            // Источник ключа для consistent_hash: ip, path, header:<имя>, cookie:<имя>
+	Sticky           StickyConfig
+	Groups           []BackendGroup // Группы приоритета; бэкенды ссылаются на них через Backend.Group
+	SlowStart        time.Duration  // Окно разогрева вернувшегося в строй бэкенда (0 - выключено)
+	Pools            []BackendPool  // Пулы для разделения трафика; бэкенды ссылаются на них через Backend.Pool
+	PoolOverride     PoolOverride
+	Mirror           MirrorConfig
+	BodyBuffering    BodyBufferingConfig
+	Streaming        StreamingConfig
+	Headers          ProxyHeadersConfig
+	Retry            RetryPolicy
+	Hedge            HedgeConfig
+	CircuitBreaker   CircuitBreakerConfig
+	OutlierDetection OutlierDetectionConfig
+	Backends         []models.Backend
 }
 
 // CreateLoadBalancers инициализирует набор балансировщиков нагрузки для каждого маршрута.
@@ -19,22 +37,47 @@ type RouteConfig struct {
 //
 //	map[string]*LoadBalancerHandler: готовые к использованию обработчики,
 //	где ключ - это путь, а значение - соответствующий LoadBalancerHandler.
+//	error: некорректная настройка маршрута или бэкенда; маршрут с ошибкой не создается.
 func CreateLoadBalancers(routes []RouteConfig,
 	registry *backends.BackendRegistry,
 	healthChecker *healthchecker.HealthChecker,
-	logger *zap.Logger) map[string]*LoadBalancerHandler {
+	logger *zap.Logger) (map[string]*LoadBalancerHandler, error) {
 
 	lbMap := make(map[string]*LoadBalancerHandler)
 
 	for _, route := range routes {
-		healthChannels := setupHealthAndRegister(route.Backends, registry, healthChecker)
+		strategy, err := NewStrategy(route)
+		if err != nil {
+			return nil, fmt.Errorf("invalid route %q: %w", route.Path, err)
+		}
 
-		lbHandler := NewLBHandler(registry, healthChannels, logger)
+		route.Backends = assignBackendIds(route.Backends)
+		healthChannels, err := setupHealthAndRegister(route.Backends, registry, healthChecker)
+		if err != nil {
+			return nil, fmt.Errorf("invalid route %q: %w", route.Path, err)
+		}
+
+		lbHandler, err := NewLBHandler(route, registry, healthChannels, strategy, logger)
+		if err != nil {
+			return nil, fmt.Errorf("invalid route %q: %w", route.Path, err)
+		}
 		lbMap[route.Path] = lbHandler
 		logger.Debug("Load balancer created for route", zap.String("path", route.Path))
 	}
 
-	return lbMap
+	return lbMap, nil
+}
+
+// assignBackendIds возвращает копию списка бэкендов, где у каждого есть уникальный Id
+func assignBackendIds(backendsConfig []models.Backend) []models.Backend {
+	result := make([]models.Backend, len(backendsConfig))
+	for i, backend := range backendsConfig {
+		if backend.Id == 0 {
+			backend.Id = backends.NextBackendId()
+		}
+		result[i] = backend
+	}
+	return result
 }
 
 // setupHealthAndRegister регистрирует бэкенды в системе и настраивает подписку на их статусы.
@@ -43,25 +86,31 @@ func CreateLoadBalancers(routes []RouteConfig,
 // 2. Регистрирует в общем реестре
 // 3. Создает подписку на изменения состояния
 //
-// Возвращает список каналов для получения обновлений о состоянии бэкендов.
-func setupHealthAndRegister(backendsConfig []models.Backend, registry *backends.BackendRegistry, healthChecker *healthchecker.HealthChecker) []<-chan models.BackendStatus {
+// Возвращает список каналов для получения обновлений о состоянии бэкендов
+// или ошибку, если проверку бэкенда невозможно настроить.
+func setupHealthAndRegister(backendsConfig []models.Backend, registry *backends.BackendRegistry, healthChecker *healthchecker.HealthChecker) ([]<-chan models.BackendStatus, error) {
 	var healthChannels []<-chan models.BackendStatus
 
 	for _, backend := range backendsConfig {
 		backendCopy := backend
-		registerBackend(&backendCopy, registry, healthChecker)
+		if err := registerBackend(&backendCopy, registry, healthChecker); err != nil {
+			return nil, fmt.Errorf("backend %s: %w", backend.URL, err)
+		}
 
 		ch := registry.Subscribe(backendCopy.Id)
 		healthChannels = append(healthChannels, ch)
 	}
 
-	return healthChannels
+	return healthChannels, nil
 }
 
 // registerBackend выполняет полную регистрацию бэкенда в системе:
 // 1. Добавляет в health checker для регулярных проверок
 // 2. Регистрирует в общем реестре бэкендов
-func registerBackend(backend *models.Backend, registry *backends.BackendRegistry, healthChecker *healthchecker.HealthChecker) {
-	healthChecker.AddBackend(backend)
+func registerBackend(backend *models.Backend, registry *backends.BackendRegistry, healthChecker *healthchecker.HealthChecker) error {
+	if err := healthChecker.AddBackend(backend); err != nil {
+		return err
+	}
 	registry.AddBackendToRegistry(*backend)
+	return nil
 }
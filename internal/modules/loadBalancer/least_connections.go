@@ -0,0 +1,77 @@
+package loadBalancer
+
+import (
+	"errors"
+	modelsBackend "lb/internal/modules/backends/models"
+	"math/rand"
+	"sync"
+	"sync/atomic"
+)
+
+// ------------------LEAST-CONNECTIONS ------------------
+// LeastConnectionsAlgorithm выбирает бэкенд с наименьшим числом незавершенных запросов.
+// Счетчики обновляются обработчиком через интерфейс RequestTracker.
+type LeastConnectionsAlgorithm struct {
+	mu       sync.RWMutex
+	inFlight map[uint64]*int64 // число запросов в обработке по Id бэкенда
+}
+
+func NewLeastConnectionsStrategy() *LeastConnectionsAlgorithm {
+	return &LeastConnectionsAlgorithm{
+		inFlight: make(map[uint64]*int64),
+	}
+}
+
+// GetNextBackend возвращает наименее загруженный бэкенд.
+// При равенстве счетчиков выбор среди лидеров случайный, чтобы не перегружать первый в списке.
+func (lc *LeastConnectionsAlgorithm) GetNextBackend(backends []*modelsBackend.Backend) (*modelsBackend.Backend, error) {
+	if len(backends) == 0 {
+		return nil, errors.New("no backends available")
+	}
+
+	var best *modelsBackend.Backend
+	var min int64
+	ties := 0
+	for _, backend := range backends {
+		active := atomic.LoadInt64(lc.counter(backend.Id))
+		switch {
+		case best == nil || active < min:
+			best, min, ties = backend, active, 1
+		case active == min:
+			// Reservoir sampling: каждый из равных получает одинаковый шанс
+			ties++
+			if rand.Intn(ties) == 0 {
+				best = backend
+			}
+		}
+	}
+	return best, nil
+}
+
+// RequestStarted увеличивает счетчик незавершенных запросов бэкенда
+func (lc *LeastConnectionsAlgorithm) RequestStarted(backend *modelsBackend.Backend) {
+	atomic.AddInt64(lc.counter(backend.Id), 1)
+}
+
+// RequestFinished уменьшает счетчик незавершенных запросов бэкенда
+func (lc *LeastConnectionsAlgorithm) RequestFinished(backend *modelsBackend.Backend) {
+	atomic.AddInt64(lc.counter(backend.Id), -1)
+}
+
+// counter возвращает счетчик бэкенда, создавая его при первом обращении
+func (lc *LeastConnectionsAlgorithm) counter(id uint64) *int64 {
+	lc.mu.RLock()
+	c, ok := lc.inFlight[id]
+	lc.mu.RUnlock()
+	if ok {
+		return c
+	}
+
+	lc.mu.Lock()
+	defer lc.mu.Unlock()
+	if c, ok = lc.inFlight[id]; !ok {
+		c = new(int64)
+		lc.inFlight[id] = c
+	}
+	return c
+}
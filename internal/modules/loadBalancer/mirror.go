@@ -0,0 +1,136 @@
+package loadBalancer
+
+import (
+	"bytes"
+	"go.uber.org/zap"
+	"io"
+	"math/rand"
+	"net/http"
+	"sync/atomic"
+	"time"
+)
+
+const (
+	defaultMirrorQueueSize = 100
+	mirrorWorkers          = 4
+	mirrorTimeout          = 5 * time.Second
+)
+
+// MirrorConfig описывает теневую отправку копий запросов на дополнительный бэкенд
+type MirrorConfig struct {
+	URL       string // Базовый URL зеркала; пустой - зеркалирование выключено
+	Percent   int    // Доля зеркалируемых запросов, 0-100
+	QueueSize int    // Размер очереди копий (по умолчанию 100)
+}
+
+// MirrorStats - счетчики зеркалирования маршрута
+type MirrorStats struct {
+	URL     string `json:"url"`
+	Percent int    `json:"percent"`
+	Queued  uint64 `json:"queued"`  // Копии, поставленные в очередь
+	Dropped uint64 `json:"dropped"` // Копии, потерянные из-за переполнения очереди
+	Failed  uint64 `json:"failed"`  // Копии, которые не удалось отправить на зеркало
+}
+
+// mirrorJob - копия запроса, ожидающая отправки на зеркало
+type mirrorJob struct {
+	method string
+	url    string
+	header http.Header
+	body   []byte
+}
+
+// trafficMirror асинхронно отправляет копии запросов на зеркало и отбрасывает ответы.
+// Очередь ограничена: при ее переполнении копия теряется, а основной запрос не ждет.
+type trafficMirror struct {
+	baseURL string
+	percent int
+	queue   chan mirrorJob
+	client  *http.Client
+	queued  uint64
+	dropped uint64
+	failed  uint64
+	logger  *zap.Logger
+}
+
+// newTrafficMirror создает зеркало и запускает его воркеры или возвращает nil, если URL не задан
+func newTrafficMirror(cfg MirrorConfig, logger *zap.Logger) *trafficMirror {
+	if cfg.URL == "" || cfg.Percent <= 0 {
+		return nil
+	}
+	queueSize := cfg.QueueSize
+	if queueSize <= 0 {
+		queueSize = defaultMirrorQueueSize
+	}
+
+	m := &trafficMirror{
+		baseURL: cfg.URL,
+		percent: cfg.Percent,
+		queue:   make(chan mirrorJob, queueSize),
+		client:  &http.Client{Timeout: mirrorTimeout},
+		logger:  logger,
+	}
+	for i := 0; i < mirrorWorkers; i++ {
+		go m.worker()
+	}
+	return m
+}
+
+// submit ставит копию клиентского запроса r в очередь с учетом процента выборки.
+// Копия строится по пути и заголовкам клиента, а не по запросу к основному бэкенду,
+// чтобы на зеркало не попадали его базовый путь и заголовки X-Forwarded-*.
+// Никогда не блокируется.
+func (m *trafficMirror) submit(r *http.Request, body []byte) {
+	if m.percent < 100 && rand.Intn(100) >= m.percent {
+		return
+	}
+
+	header := r.Header.Clone()
+	removeHopHeaders(header)
+	job := mirrorJob{
+		method: r.Method,
+		url:    buildTargetURL(m.baseURL, r.URL.Path, r.URL.RawQuery),
+		header: header,
+		body:   body,
+	}
+	select {
+	case m.queue <- job:
+		atomic.AddUint64(&m.queued, 1)
+	default:
+		atomic.AddUint64(&m.dropped, 1)
+		m.logger.Debug("Mirror queue is full, dropping request copy", zap.String("url", job.url))
+	}
+}
+
+// worker отправляет копии из очереди и дочитывает ответы, чтобы переиспользовать соединения
+func (m *trafficMirror) worker() {
+	for job := range m.queue {
+		req, err := http.NewRequest(job.method, job.url, bytes.NewReader(job.body))
+		if err != nil {
+			atomic.AddUint64(&m.failed, 1)
+			m.logger.Debug("Failed to build mirror request", zap.Error(err))
+			continue
+		}
+		req.Header = job.header
+
+		resp, err := m.client.Do(req)
+		if err != nil {
+			atomic.AddUint64(&m.failed, 1)
+			m.logger.Debug("Mirror request failed", zap.String("url", job.url), zap.Error(err))
+			continue
+		}
+		io.Copy(io.Discard, resp.Body)
+		resp.Body.Close()
+	}
+}
+
+// stats возвращает текущие значения счетчиков зеркала
+func (m *trafficMirror) stats() MirrorStats {
+	return MirrorStats{
+		URL:     m.baseURL,
+		Percent: m.percent,
+		Queued:  atomic.LoadUint64(&m.queued),
+		Dropped: atomic.LoadUint64(&m.dropped),
+		Failed:  atomic.LoadUint64(&m.failed),
+	}
+}
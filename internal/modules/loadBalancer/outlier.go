@@ -0,0 +1,267 @@
+package loadBalancer
+
+import (
+	"fmt"
+	"go.uber.org/zap"
+	"lb/internal/modules/backends"
+	"lb/internal/modules/backends/models"
+	"net/http"
+	"sort"
+	"sync"
+	"time"
+)
+
+const (
+	defaultOutlierConsecutive5xx     = 5
+	defaultOutlierConsecutiveGateway = 3
+	defaultOutlierBaseEjectionTime   = 30 * time.Second
+	defaultOutlierMaxEjectionTime    = 5 * time.Minute
+	defaultOutlierMaxEjectionPercent = 50
+	// outlierLatencyDecay - вес нового замера в скользящем среднем задержки
+	outlierLatencyDecay = 0.2
+	// outlierMinLatencySamples - замеров, после которых задержка бэкенда сравнивается с пулом
+	outlierMinLatencySamples = 10
+)
+
+// OutlierDetectionConfig задает пассивную проверку бэкендов по результатам живых запросов.
+// Бэкенд исключается после серии ошибок подряд или если его средняя задержка
+// превышает медиану пула в LatencyFactor раз. Время исключения удваивается
+// с каждым повторным исключением до MaxEjectionTime.
+type OutlierDetectionConfig struct {
+	Enabled                  bool
+	Consecutive5xx           int           // Ответов 5xx и ошибок транспорта подряд (по умолчанию 5, отрицательное - не учитывать)
+	ConsecutiveGatewayErrors int           // Ответов 502/503/504 и ошибок транспорта подряд (по умолчанию 3, отрицательное - не учитывать)
+	LatencyFactor            float64       // Во сколько раз задержка выше медианы пула (0 - не учитывать)
+	BaseEjectionTime         time.Duration // Время первого исключения (по умолчанию 30s)
+	MaxEjectionTime          time.Duration // Предел времени исключения (по умолчанию 5m)
+	MaxEjectionPercent       int           // Доля бэкендов маршрута, которую можно исключить (по умолчанию 50, см. newOutlierDetector)
+}
+
+// Validate проверяет пороги и интервалы пассивной проверки
+func (c OutlierDetectionConfig) Validate() error {
+	if !c.Enabled {
+		return nil
+	}
+	if c.LatencyFactor != 0 && c.LatencyFactor <= 1 {
+		return fmt.Errorf("outlier latency factor must be greater than 1")
+	}
+	if c.BaseEjectionTime < 0 || c.MaxEjectionTime < 0 {
+		return fmt.Errorf("outlier ejection times must not be negative")
+	}
+	if c.MaxEjectionPercent < 0 || c.MaxEjectionPercent > 100 {
+		return fmt.Errorf("outlier max ejection percent must be in [0, 100]")
+	}
+	return nil
+}
+
+// outlierStats - наблюдения за одним бэкендом
+type outlierStats struct {
+	url             string
+	consecutive5xx  int
+	consecutiveGw   int
+	latency         float64 // скользящее среднее задержки в секундах
+	samples         int
+	ejected         bool
+	ejections       int // число исключений подряд, определяет длительность следующего
+	lastReadmission time.Time
+}
+
+// outlierDetector исключает бэкенды маршрута через BackendRegistry.UpdateHealth,
+// чтобы исключение видели все подписчики
+type outlierDetector struct {
+	mu               sync.Mutex
+	consecutive5xx   int
+	consecutiveGw    int
+	latencyFactor    float64
+	baseEjectionTime time.Duration
+	maxEjectionTime  time.Duration
+	maxEjected       int
+	backends         map[uint64]*outlierStats
+	registry         *backends.BackendRegistry
+	logger           *zap.Logger
+}
+
+// newOutlierDetector возвращает nil, если пассивная проверка выключена.
+// routeBackends - число бэкендов маршрута для расчета предела исключений.
+func newOutlierDetector(cfg OutlierDetectionConfig, routeBackends int, registry *backends.BackendRegistry, logger *zap.Logger) *outlierDetector {
+	if !cfg.Enabled {
+		return nil
+	}
+	d := &outlierDetector{
+		consecutive5xx:   cfg.Consecutive5xx,
+		consecutiveGw:    cfg.ConsecutiveGatewayErrors,
+		latencyFactor:    cfg.LatencyFactor,
+		baseEjectionTime: cfg.BaseEjectionTime,
+		maxEjectionTime:  cfg.MaxEjectionTime,
+		backends:         make(map[uint64]*outlierStats),
+		registry:         registry,
+		logger:           logger,
+	}
+	if d.consecutive5xx == 0 {
+		d.consecutive5xx = defaultOutlierConsecutive5xx
+	}
+	if d.consecutiveGw == 0 {
+		d.consecutiveGw = defaultOutlierConsecutiveGateway
+	}
+	if d.baseEjectionTime == 0 {
+		d.baseEjectionTime = defaultOutlierBaseEjectionTime
+	}
+	if d.maxEjectionTime == 0 {
+		d.maxEjectionTime = defaultOutlierMaxEjectionTime
+	}
+	percent := cfg.MaxEjectionPercent
+	if percent == 0 {
+		percent = defaultOutlierMaxEjectionPercent
+	}
+	// Как минимум один бэкенд можно исключить при любом проценте. Исключение
+	// единственного бэкенда маршрута оставило бы его без бэкендов, поэтому оно
+	// возможно только при явном max_ejection_percent: 100.
+	d.maxEjected = routeBackends * percent / 100
+	if d.maxEjected < 1 && routeBackends > 1 {
+		d.maxEjected = 1
+	}
+	return d
+}
+
+// record учитывает результат запроса к бэкенду: status - код ответа (0 при ошибке транспорта).
+// Исключение публикуется в реестр после снятия mu, чтобы медленный подписчик
+// не задерживал учет результатов других запросов.
+func (d *outlierDetector) record(backend *models.Backend, status int, latency time.Duration) {
+	d.mu.Lock()
+	duration, ejected := d.observe(backend, status, latency)
+	d.mu.Unlock()
+
+	if ejected {
+		d.registry.UpdateHealth(models.BackendStatus{Id: backend.Id, IsHealthy: false, Source: models.SourceOutlier})
+		time.AfterFunc(duration, func() { d.readmit(backend.Id) })
+	}
+}
+
+// observe обновляет наблюдения за бэкендом и решает, исключать ли его.
+// Возвращает длительность исключения и true, если бэкенд исключен. Вызывается под mu.
+func (d *outlierDetector) observe(backend *models.Backend, status int, latency time.Duration) (time.Duration, bool) {
+	stats, ok := d.backends[backend.Id]
+	if !ok {
+		stats = &outlierStats{url: backend.URL}
+		d.backends[backend.Id] = stats
+	}
+	// Запросы, начатые до исключения, ничего не меняют
+	if stats.ejected {
+		return 0, false
+	}
+
+	gateway := status == 0 || status == http.StatusBadGateway || status == http.StatusServiceUnavailable || status == http.StatusGatewayTimeout
+	if status == 0 || status >= http.StatusInternalServerError {
+		stats.consecutive5xx++
+	} else {
+		stats.consecutive5xx = 0
+	}
+	if gateway {
+		stats.consecutiveGw++
+	} else {
+		stats.consecutiveGw = 0
+	}
+
+	switch {
+	case d.consecutiveGw > 0 && stats.consecutiveGw >= d.consecutiveGw:
+		return d.eject(stats, "consecutive gateway errors")
+	case d.consecutive5xx > 0 && stats.consecutive5xx >= d.consecutive5xx:
+		return d.eject(stats, "consecutive 5xx")
+	}
+
+	// Задержку учитываем только по полученным ответам
+	if status == 0 || d.latencyFactor == 0 {
+		return 0, false
+	}
+	if stats.samples == 0 {
+		stats.latency = latency.Seconds()
+	} else {
+		stats.latency = outlierLatencyDecay*latency.Seconds() + (1-outlierLatencyDecay)*stats.latency
+	}
+	stats.samples++
+	if stats.samples >= outlierMinLatencySamples {
+		if median, ok := d.medianLatency(); ok && stats.latency > d.latencyFactor*median {
+			return d.eject(stats, "latency above pool median")
+		}
+	}
+	return 0, false
+}
+
+// medianLatency возвращает медиану средних задержек бэкендов пула, набравших достаточно замеров.
+// Медиана считается, только если таких бэкендов не меньше трех. Вызывается под mu.
+func (d *outlierDetector) medianLatency() (float64, bool) {
+	latencies := make([]float64, 0, len(d.backends))
+	for _, stats := range d.backends {
+		if !stats.ejected && stats.samples >= outlierMinLatencySamples {
+			latencies = append(latencies, stats.latency)
+		}
+	}
+	if len(latencies) < 3 {
+		return 0, false
+	}
+	sort.Float64s(latencies)
+	return latencies[len(latencies)/2], true
+}
+
+// eject помечает бэкенд исключенным на время, растущее с каждым исключением подряд,
+// и возвращает это время; false - предел исключений достигнут. Вызывается под mu.
+func (d *outlierDetector) eject(stats *outlierStats, reason string) (time.Duration, bool) {
+	ejected := 0
+	for _, other := range d.backends {
+		if other.ejected {
+			ejected++
+		}
+	}
+	if ejected >= d.maxEjected {
+		d.logger.Warn("Outlier ejection skipped: max ejection percent reached",
+			zap.String("backend", stats.url),
+			zap.String("reason", reason),
+		)
+		d.resetCounters(stats)
+		return 0, false
+	}
+
+	// Бэкенд долго работал без исключений - начинаем отсчет заново
+	if !stats.lastReadmission.IsZero() && time.Since(stats.lastReadmission) > d.maxEjectionTime {
+		stats.ejections = 0
+	}
+	stats.ejections++
+	duration := d.baseEjectionTime
+	for i := 1; i < stats.ejections && duration < d.maxEjectionTime; i++ {
+		duration *= 2
+	}
+	if duration > d.maxEjectionTime {
+		duration = d.maxEjectionTime
+	}
+
+	stats.ejected = true
+	d.resetCounters(stats)
+	d.logger.Warn("Backend ejected by outlier detection",
+		zap.String("backend", stats.url),
+		zap.String("reason", reason),
+		zap.Int("ejections", stats.ejections),
+		zap.Duration("duration", duration),
+	)
+	return duration, true
+}
+
+// readmit возвращает бэкенд после окончания исключения
+func (d *outlierDetector) readmit(id uint64) {
+	d.mu.Lock()
+	stats := d.backends[id]
+	stats.ejected = false
+	stats.lastReadmission = time.Now()
+	url := stats.url
+	d.mu.Unlock()
+
+	d.logger.Info("Backend re-admitted after outlier ejection", zap.String("backend", url))
+	d.registry.UpdateHealth(models.BackendStatus{Id: id, IsHealthy: true, Source: models.SourceOutlier})
+}
+
+// resetCounters сбрасывает серии ошибок и статистику задержки бэкенда
+func (d *outlierDetector) resetCounters(stats *outlierStats) {
+	stats.consecutive5xx = 0
+	stats.consecutiveGw = 0
+	stats.latency = 0
+	stats.samples = 0
+}
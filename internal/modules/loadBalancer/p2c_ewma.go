@@ -0,0 +1,101 @@
+package loadBalancer
+
+import (
+	"errors"
+	modelsBackend "lb/internal/modules/backends/models"
+	"math/rand"
+	"sync"
+	"time"
+)
+
+const (
+	// defaultEWMADecay - вес нового замера в скользящем среднем
+	defaultEWMADecay = 0.3
+	// defaultErrorPenalty - штрафная задержка, добавляемая к оценке пропорционально доле ошибок
+	defaultErrorPenalty = 5 * time.Second
+)
+
+// ------------------POWER OF TWO CHOICES + EWMA ------------------
+// P2CEWMAAlgorithm выбирает два случайных бэкенда и отдает запрос тому,
+// у которого лучше оценка по скользящему среднему задержки и доле ошибок.
+// Случайная пара сглаживает "стадный" эффект чистого выбора по минимальной задержке.
+type P2CEWMAAlgorithm struct {
+	mu           sync.Mutex
+	stats        map[uint64]*ewmaStats
+	decay        float64
+	errorPenalty time.Duration
+}
+
+// ewmaStats хранит скользящие средние по одному бэкенду
+type ewmaStats struct {
+	latency   float64 // средняя задержка в секундах
+	errorRate float64 // доля неуспешных ответов от 0 до 1
+	observed  bool
+}
+
+func NewP2CEWMAStrategy() *P2CEWMAAlgorithm {
+	return &P2CEWMAAlgorithm{
+		stats:        make(map[uint64]*ewmaStats),
+		decay:        defaultEWMADecay,
+		errorPenalty: defaultErrorPenalty,
+	}
+}
+
+// GetNextBackend сравнивает два случайно выбранных разных бэкенда
+// и возвращает тот, у которого оценка меньше
+func (p *P2CEWMAAlgorithm) GetNextBackend(backends []*modelsBackend.Backend) (*modelsBackend.Backend, error) {
+	if len(backends) == 0 {
+		return nil, errors.New("no backends available")
+	}
+	if len(backends) == 1 {
+		return backends[0], nil
+	}
+
+	i := rand.Intn(len(backends))
+	j := rand.Intn(len(backends) - 1)
+	if j >= i {
+		j++
+	}
+	first, second := backends[i], backends[j]
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if p.score(second.Id) < p.score(first.Id) {
+		return second, nil
+	}
+	return first, nil
+}
+
+// ObserveResult учитывает результат запроса в скользящих средних бэкенда
+func (p *P2CEWMAAlgorithm) ObserveResult(backend *modelsBackend.Backend, latency time.Duration, failed bool) {
+	errorSample := 0.0
+	if failed {
+		errorSample = 1
+	}
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	s, ok := p.stats[backend.Id]
+	if !ok {
+		s = &ewmaStats{}
+		p.stats[backend.Id] = s
+	}
+	// Первый замер берем как есть, чтобы не тянуть среднее от нуля
+	if !s.observed {
+		s.latency, s.errorRate, s.observed = latency.Seconds(), errorSample, true
+		return
+	}
+	s.latency += p.decay * (latency.Seconds() - s.latency)
+	s.errorRate += p.decay * (errorSample - s.errorRate)
+}
+
+// score возвращает оценку бэкенда: меньше - лучше.
+// Бэкенды без замеров получают нулевую оценку и быстро попадают в выборку.
+func (p *P2CEWMAAlgorithm) score(id uint64) float64 {
+	s, ok := p.stats[id]
+	if !ok {
+		return 0
+	}
+	return s.latency + s.errorRate*p.errorPenalty.Seconds()
+}
@@ -0,0 +1,243 @@
+package loadBalancer
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"math/rand"
+	"net"
+	"net/http"
+	"sync/atomic"
+	"syscall"
+	"time"
+)
+
+// Классы ошибок транспорта, по которым можно повторять запрос
+const (
+	RetryErrorConnect = "connect" // не удалось установить соединение
+	RetryErrorTimeout = "timeout" // попытка не уложилась в таймаут
+	RetryErrorReset   = "reset"   // соединение оборвано бэкендом
+	RetryErrorOther   = "other"   // прочие ошибки транспорта
+)
+
+const (
+	defaultRetryAttempts    = 3
+	defaultRetryBackoffBase = 100 * time.Millisecond
+	defaultRetryBackoffMax  = 2 * time.Second
+)
+
+var (
+	defaultRetryStatuses = []int{http.StatusTooManyRequests, http.StatusBadGateway, http.StatusServiceUnavailable, http.StatusGatewayTimeout}
+	defaultRetryErrors   = []string{RetryErrorConnect, RetryErrorTimeout, RetryErrorReset}
+)
+
+// RetryPolicy задает повторные попытки запроса маршрута.
+// Каждая повторная попытка по возможности уходит на другой здоровый бэкенд.
+type RetryPolicy struct {
+	MaxAttempts    int           // Всего попыток, включая первую (по умолчанию 3)
+	RetryOnStatus  []int         // Статусы ответа, после которых запрос повторяется (по умолчанию 429, 502, 503, 504)
+	RetryOnErrors  []string      // Классы ошибок: connect, timeout, reset, other (по умолчанию первые три)
+	IdempotentOnly bool          // Повторять только идемпотентные методы и запросы с Idempotency-Key
+	BackoffBase    time.Duration // Задержка перед второй попыткой, далее удваивается (по умолчанию 100ms)
+	BackoffMax     time.Duration // Верхняя граница задержки (по умолчанию 2s)
+	Jitter         time.Duration // Случайная добавка к задержке от 0 до Jitter
+	Budget         RetryBudget   // Ограничение доли повторов (по умолчанию не ограничена)
+}
+
+// RetryStats - счетчики попыток маршрута
+type RetryStats struct {
+	Requests  uint64 `json:"requests"`  // Запросы, дошедшие до бэкендов
+	Attempts  uint64 `json:"attempts"`  // Все попытки, включая первые
+	Retries   uint64 `json:"retries"`   // Повторные попытки
+	Exhausted uint64 `json:"exhausted"` // Запросы, для которых попытки закончились неудачей
+	Denied    uint64 `json:"denied"`    // Повторы, не разрешенные бюджетом
+}
+
+// retryPolicy - подготовленная политика повторов с счетчиками
+type retryPolicy struct {
+	maxAttempts    int
+	statuses       map[int]struct{}
+	errorClasses   map[string]struct{}
+	idempotentOnly bool
+	backoffBase    time.Duration
+	backoffMax     time.Duration
+	jitter         time.Duration
+	budget         *retryBudget // nil, если бюджет не задан
+
+	requests  uint64
+	attempts  uint64
+	retries   uint64
+	exhausted uint64
+	denied    uint64
+}
+
+// Validate проверяет число попыток, статусы, классы ошибок и бюджет
+func (p RetryPolicy) Validate() error {
+	_, err := newRetryPolicy(p)
+	return err
+}
+
+// newRetryPolicy проверяет политику и подставляет значения по умолчанию
+func newRetryPolicy(cfg RetryPolicy) (*retryPolicy, error) {
+	if cfg.MaxAttempts < 0 {
+		return nil, fmt.Errorf("max attempts must not be negative")
+	}
+	if cfg.BackoffBase < 0 || cfg.BackoffMax < 0 || cfg.Jitter < 0 {
+		return nil, fmt.Errorf("backoff durations must not be negative")
+	}
+	if err := cfg.Budget.validate(); err != nil {
+		return nil, err
+	}
+
+	p := &retryPolicy{
+		maxAttempts:    cfg.MaxAttempts,
+		statuses:       make(map[int]struct{}),
+		errorClasses:   make(map[string]struct{}),
+		idempotentOnly: cfg.IdempotentOnly,
+		backoffBase:    cfg.BackoffBase,
+		backoffMax:     cfg.BackoffMax,
+		jitter:         cfg.Jitter,
+	}
+	if p.maxAttempts == 0 {
+		p.maxAttempts = defaultRetryAttempts
+	}
+	if p.backoffBase == 0 {
+		p.backoffBase = defaultRetryBackoffBase
+	}
+	if p.backoffMax == 0 {
+		p.backoffMax = defaultRetryBackoffMax
+	}
+	if cfg.Budget.enabled() {
+		p.budget = newRetryBudget(cfg.Budget)
+	}
+
+	statuses := cfg.RetryOnStatus
+	if statuses == nil {
+		statuses = defaultRetryStatuses
+	}
+	for _, status := range statuses {
+		if status < 100 || status > 599 {
+			return nil, fmt.Errorf("invalid retriable status %d", status)
+		}
+		p.statuses[status] = struct{}{}
+	}
+
+	classes := cfg.RetryOnErrors
+	if classes == nil {
+		classes = defaultRetryErrors
+	}
+	for _, class := range classes {
+		switch class {
+		case RetryErrorConnect, RetryErrorTimeout, RetryErrorReset, RetryErrorOther:
+			p.errorClasses[class] = struct{}{}
+		default:
+			return nil, fmt.Errorf("unknown retriable error class %q", class)
+		}
+	}
+	return p, nil
+}
+
+// attemptsFor возвращает число попыток для запроса: потоковое тело
+// и неидемпотентный метод (если так настроено) отправляются один раз
+func (p *retryPolicy) attemptsFor(r *http.Request, body *proxyBody) int {
+	if !body.replayable() {
+		return 1
+	}
+	if p.idempotentOnly && !isIdempotent(r) {
+		return 1
+	}
+	return p.maxAttempts
+}
+
+// recordRequest учитывает первичный запрос в счетчиках и бюджете
+func (p *retryPolicy) recordRequest() {
+	atomic.AddUint64(&p.requests, 1)
+	if p.budget != nil {
+		p.budget.recordRequest()
+	}
+}
+
+// allowRetry проверяет бюджет повторов; отказ учитывается в счетчике denied
+func (p *retryPolicy) allowRetry() bool {
+	if p.budget == nil || p.budget.tryRetry() {
+		return true
+	}
+	atomic.AddUint64(&p.denied, 1)
+	return false
+}
+
+// retriableStatus сообщает, стоит ли повторять запрос после ответа с этим статусом
+func (p *retryPolicy) retriableStatus(status int) bool {
+	_, ok := p.statuses[status]
+	return ok
+}
+
+// retriableError сообщает, стоит ли повторять запрос после ошибки транспорта
+func (p *retryPolicy) retriableError(ctx context.Context, err error) bool {
+	// Клиент ушел или запрос отменен - повторять некому
+	if ctx.Err() != nil {
+		return false
+	}
+	_, ok := p.errorClasses[classifyError(ctx, err)]
+	return ok
+}
+
+// backoff возвращает задержку перед попыткой attempt (начиная с 1 для первого повтора):
+// экспоненциальный рост от base с ограничением max и случайной добавкой
+func (p *retryPolicy) backoff(attempt int) time.Duration {
+	delay := p.backoffBase
+	for i := 1; i < attempt && delay < p.backoffMax; i++ {
+		delay *= 2
+	}
+	if delay > p.backoffMax {
+		delay = p.backoffMax
+	}
+	if p.jitter > 0 {
+		delay += time.Duration(rand.Int63n(int64(p.jitter)))
+	}
+	return delay
+}
+
+// stats возвращает текущие значения счетчиков
+func (p *retryPolicy) stats() RetryStats {
+	return RetryStats{
+		Requests:  atomic.LoadUint64(&p.requests),
+		Attempts:  atomic.LoadUint64(&p.attempts),
+		Retries:   atomic.LoadUint64(&p.retries),
+		Exhausted: atomic.LoadUint64(&p.exhausted),
+		Denied:    atomic.LoadUint64(&p.denied),
+	}
+}
+
+// classifyError относит ошибку транспорта к одному из классов RetryError*
+func classifyError(ctx context.Context, err error) string {
+	var netErr net.Error
+	var opErr *net.OpError
+	switch {
+	// Попытку отменил таймер doWithTimeout, а не клиент
+	case errors.Is(err, context.Canceled) && ctx.Err() == nil,
+		errors.Is(err, context.DeadlineExceeded),
+		errors.As(err, &netErr) && netErr.Timeout():
+		return RetryErrorTimeout
+	case errors.As(err, &opErr) && opErr.Op == "dial",
+		errors.Is(err, syscall.ECONNREFUSED):
+		return RetryErrorConnect
+	case errors.Is(err, syscall.ECONNRESET),
+		errors.Is(err, syscall.EPIPE),
+		errors.Is(err, io.EOF),
+		errors.Is(err, io.ErrUnexpectedEOF):
+		return RetryErrorReset
+	default:
+		return RetryErrorOther
+	}
+}
+
+// isIdempotent определяет, можно ли безопасно повторить запрос (RFC 9110, 9.2.2)
+func isIdempotent(r *http.Request) bool {
+	switch r.Method {
+	case http.MethodGet, http.MethodHead, http.MethodOptions, http.MethodTrace, http.MethodPut, http.MethodDelete:
+		return true
+	}
+	return r.Header.Get("Idempotency-Key") != "" || r.Header.Get("X-Idempotency-Key") != ""
+}
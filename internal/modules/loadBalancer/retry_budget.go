@@ -0,0 +1,82 @@
+package loadBalancer
+
+import (
+	"fmt"
+	"sync"
+	"time"
+)
+
+const defaultRetryBudgetWindow = 10 * time.Second
+
+// RetryBudget ограничивает долю повторов, чтобы деградация пула не превращалась в шторм:
+// повтор разрешен, пока повторов в окне меньше Ratio от первичных запросов
+// плюс MinRetriesPerSecond на каждую секунду окна
+type RetryBudget struct {
+	Ratio               float64       // Допустимая доля повторов от первичных запросов, например 0.2
+	MinRetriesPerSecond int           // Повторы в секунду, разрешенные при любом трафике
+	Window              time.Duration // Скользящее окно учета (по умолчанию 10s)
+}
+
+// enabled сообщает, задан ли бюджет; без бюджета повторы ограничены только политикой
+func (b RetryBudget) enabled() bool {
+	return b.Ratio > 0 || b.MinRetriesPerSecond > 0
+}
+
+// validate проверяет параметры бюджета
+func (b RetryBudget) validate() error {
+	if b.Ratio < 0 {
+		return fmt.Errorf("budget ratio must not be negative")
+	}
+	if b.MinRetriesPerSecond < 0 {
+		return fmt.Errorf("budget min retries per second must not be negative")
+	}
+	if b.Window < 0 {
+		return fmt.Errorf("budget window must not be negative")
+	}
+	if b.Window > 0 && b.Window < time.Second {
+		return fmt.Errorf("budget window must be at least 1s")
+	}
+	return nil
+}
+
+// retryBudget считает первичные запросы и повторы маршрута в скользящем окне
+type retryBudget struct {
+	mu           sync.Mutex
+	ratio        float64
+	minPerSecond int
+	window       *slidingWindow // requests - первичные запросы, marked - повторы
+}
+
+func newRetryBudget(cfg RetryBudget) *retryBudget {
+	window := cfg.Window
+	if window == 0 {
+		window = defaultRetryBudgetWindow
+	}
+	return &retryBudget{
+		ratio:        cfg.Ratio,
+		minPerSecond: cfg.MinRetriesPerSecond,
+		window:       newSlidingWindow(window),
+	}
+}
+
+// recordRequest учитывает первичный запрос
+func (b *retryBudget) recordRequest() {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.window.add(time.Now().Unix(), 1, 0)
+}
+
+// tryRetry проверяет бюджет и, если повтор разрешен, сразу учитывает его
+func (b *retryBudget) tryRetry() bool {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	sec := time.Now().Unix()
+	counts := b.window.sum(sec)
+	allowed := b.ratio*float64(counts.requests) + float64(b.minPerSecond*b.window.seconds())
+	if float64(counts.marked) >= allowed {
+		return false
+	}
+	b.window.add(sec, 0, 1)
+	return true
+}
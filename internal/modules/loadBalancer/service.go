@@ -5,8 +5,10 @@ import (
 	"go.uber.org/zap"
 	"lb/internal/modules/backends"
 	modelsBackend "lb/internal/modules/backends/models"
+	"net/http"
 	"sync"
 	"sync/atomic"
+	"time"
 )
 
 // ------------------ROUND-ROBIN ------------------
@@ -35,6 +37,32 @@ type LoadBalancingStrategy interface {
 	GetNextBackend([]*modelsBackend.Backend) (*modelsBackend.Backend, error)
 }
 
+// RequestAwareStrategy - необязательный интерфейс стратегии, которой
+// для выбора бэкенда нужен сам запрос (например, консистентное хэширование)
+type RequestAwareStrategy interface {
+	GetBackendForRequest(r *http.Request, backends []*modelsBackend.Backend) (*modelsBackend.Backend, error)
+}
+
+// RequestTracker - необязательный интерфейс стратегии, которой нужно знать
+// о начале и завершении каждого проксируемого запроса (например, least connections)
+type RequestTracker interface {
+	RequestStarted(backend *modelsBackend.Backend)
+	RequestFinished(backend *modelsBackend.Backend)
+}
+
+// ResultObserver - необязательный интерфейс стратегии, учитывающей
+// задержку и успешность ответов бэкендов (например, P2C + EWMA)
+type ResultObserver interface {
+	ObserveResult(backend *modelsBackend.Backend, latency time.Duration, failed bool)
+}
+
+// WeightScaledStrategy - необязательный интерфейс стратегии, которая сама учитывает
+// поправочный коэффициент веса бэкенда (например, разогрев slow start).
+// scale возвращает долю эффективного веса бэкенда в диапазоне (0, 1].
+type WeightScaledStrategy interface {
+	SetWeightScale(scale func(backend *modelsBackend.Backend) float64)
+}
+
 //--------------------------------------------------
 
 type Loadbalancer struct {
@@ -43,20 +71,31 @@ type Loadbalancer struct {
 	Algorithm            LoadBalancingStrategy
 	healthUpdateChannels []<-chan modelsBackend.BackendStatus
 	healthyBackends      []*modelsBackend.Backend
+	tiers                *tierSet                 // nil, если у маршрута нет групп приоритета
+	activeGroup          string                   // группа, обслуживающая трафик сейчас
+	activeBackends       []*modelsBackend.Backend // здоровые бэкенды активной группы
+	slowStart            *slowStart               // nil, если разогрев выключен
 	mu                   sync.RWMutex
 }
 
 // NewLoadBalancer конструктор балансировщика
+// route: конфигурация маршрута (группы приоритета)
 // registry: источник конфигурации бэкендов
 // healthChannels: каналы обновления статусов
+// algorithm: стратегия балансировки маршрута
 // logger: настроенный экземпляр логгера
-func NewLoadBalancer(registry *backends.BackendRegistry, healthChannels []<-chan modelsBackend.BackendStatus, logger *zap.Logger) *Loadbalancer {
+func NewLoadBalancer(route RouteConfig, registry *backends.BackendRegistry, healthChannels []<-chan modelsBackend.BackendStatus, algorithm LoadBalancingStrategy, logger *zap.Logger) *Loadbalancer {
 	lb := &Loadbalancer{
 		BackendRegistry:      registry,
-		Algorithm:            NewRoundRobinStrategy(),
+		Algorithm:            algorithm,
 		healthUpdateChannels: healthChannels,
+		tiers:                newTierSet(route.Groups, route.Backends),
+		slowStart:            newSlowStart(route.SlowStart),
 		logger:               logger,
 	}
+	if scaled, ok := algorithm.(WeightScaledStrategy); ok && lb.slowStart != nil {
+		scaled.SetWeightScale(lb.slowStartFactor)
+	}
 	var wg sync.WaitGroup
 	wg.Add(len(lb.healthUpdateChannels) + 1)
 	go lb.listenToHealthUpdates(&wg)
@@ -91,14 +130,15 @@ func (lb *Loadbalancer) updateProcess(update modelsBackend.BackendStatus) {
 }
 
 // addToHealthyBacks поддерживает актуальный список здоровых нод
-// Использует мьютекс для защиты от конкурентного доступа
+// Использует мьютекс для защиты от конкурентного доступа.
+// Бэкенд берется из реестра целиком, поэтому его вес сохраняется между сменами статуса.
 func (lb *Loadbalancer) addToHealthyBacks(id uint64) {
 	lb.mu.Lock()
 	defer lb.mu.Unlock()
 
 	for _, backend := range lb.healthyBackends {
 		if backend.Id == id {
-			lb.healthyBackends = append(lb.healthyBackends, backend)
+			return
 		}
 	}
 
@@ -109,27 +149,85 @@ func (lb *Loadbalancer) addToHealthyBacks(id uint64) {
 	}
 
 	lb.healthyBackends = append(lb.healthyBackends, &backend)
+	if lb.slowStart != nil {
+		lb.slowStart.markHealthy(id)
+	}
+	lb.refreshActiveBackends()
 	lb.logger.Info("Added healthy backend! Backend id: ", zap.Uint64("id", id))
 }
 
 // removeFromHealthyBackends удаляет нездоровые ноды
-// Использует работу со срезами для эффективного удаления (порядок не сохраняется)
+// Создает новый срез, чтобы не портить список, уже выданный getHealthyBackends
 func (lb *Loadbalancer) removeFromHealthyBackends(backendId uint64) {
 	lb.mu.Lock()
 	defer lb.mu.Unlock()
 
 	for i := 0; i < len(lb.healthyBackends); i++ {
 		if lb.healthyBackends[i].Id == backendId {
-			copy(lb.healthyBackends[i:], lb.healthyBackends[i+1:])
-			lb.healthyBackends = lb.healthyBackends[:len(lb.healthyBackends)-1]
+			updated := make([]*modelsBackend.Backend, 0, len(lb.healthyBackends)-1)
+			updated = append(updated, lb.healthyBackends[:i]...)
+			updated = append(updated, lb.healthyBackends[i+1:]...)
+			lb.healthyBackends = updated
+			lb.refreshActiveBackends()
 			return
 		}
 	}
 }
 
-// getHealthyBackends возвращает текущий список здоровых бэкендов
+// refreshActiveBackends пересчитывает активную группу приоритета.
+// Вызывается под lb.mu после любого изменения списка здоровых бэкендов.
+func (lb *Loadbalancer) refreshActiveBackends() {
+	if lb.tiers == nil {
+		lb.activeBackends = lb.healthyBackends
+		return
+	}
+
+	group, active := lb.tiers.selectActive(lb.healthyBackends)
+	if group != lb.activeGroup {
+		lb.logger.Warn("Active backend tier changed",
+			zap.String("from", lb.activeGroup),
+			zap.String("to", group),
+			zap.Int("healthy", len(active)),
+		)
+	}
+	lb.activeGroup, lb.activeBackends = group, active
+}
+
+// getHealthyBackends возвращает здоровые бэкенды, которые сейчас обслуживают трафик
+// (с учетом групп приоритета)
 func (lb *Loadbalancer) getHealthyBackends() []*modelsBackend.Backend {
 	lb.mu.RLock()
 	defer lb.mu.RUnlock()
-	return lb.healthyBackends
+	return lb.activeBackends
+}
+
+// slowStartFactor возвращает долю эффективного веса бэкенда с учетом разогрева
+func (lb *Loadbalancer) slowStartFactor(backend *modelsBackend.Backend) float64 {
+	if lb.slowStart == nil {
+		return 1
+	}
+	return lb.slowStart.factor(backend.Id)
+}
+
+// slowStartCandidates готовит кандидатов для стратегии, не учитывающей разогрев сама:
+// разогревающиеся бэкенды отсеиваются пропорционально недостающей доле веса
+func (lb *Loadbalancer) slowStartCandidates(backends []*modelsBackend.Backend) []*modelsBackend.Backend {
+	if lb.slowStart == nil || len(backends) < 2 {
+		return backends
+	}
+	if _, ok := lb.Algorithm.(WeightScaledStrategy); ok {
+		return backends
+	}
+	return lb.slowStart.filter(backends)
+}
+
+// tierStatus возвращает состояние групп приоритета или nil, если они не заданы
+func (lb *Loadbalancer) tierStatus() *TierStatus {
+	if lb.tiers == nil {
+		return nil
+	}
+	lb.mu.RLock()
+	defer lb.mu.RUnlock()
+	status := lb.tiers.status(lb.activeGroup, lb.healthyBackends)
+	return &status
 }
@@ -0,0 +1,66 @@
+package loadBalancer
+
+import "time"
+
+// windowCounts - счетчики скользящего окна: запросы и отмеченные события
+// (ошибки для автомата размыкания, повторы для бюджета повторов)
+type windowCounts struct {
+	requests int
+	marked   int
+}
+
+// windowBucket - счетчики одной секунды окна
+type windowBucket struct {
+	second int64
+	windowCounts
+}
+
+// slidingWindow считает события в посекундных корзинах скользящего окна.
+// Не синхронизирован: доступ защищает владелец окна.
+type slidingWindow struct {
+	buckets []windowBucket
+}
+
+// newSlidingWindow создает окно длиной window с точностью до секунды (не меньше одной секунды)
+func newSlidingWindow(window time.Duration) *slidingWindow {
+	seconds := int(window / time.Second)
+	if seconds < 1 {
+		seconds = 1
+	}
+	return &slidingWindow{buckets: make([]windowBucket, seconds)}
+}
+
+// add учитывает requests запросов и marked отмеченных событий в секунде sec
+func (w *slidingWindow) add(sec int64, requests, marked int) {
+	bucket := &w.buckets[sec%int64(len(w.buckets))]
+	// Корзина осталась от прошлого круга - начинаем ее заново
+	if bucket.second != sec {
+		*bucket = windowBucket{second: sec}
+	}
+	bucket.requests += requests
+	bucket.marked += marked
+}
+
+// sum суммирует счетчики корзин, попадающих в окно, заканчивающееся секундой sec
+func (w *slidingWindow) sum(sec int64) windowCounts {
+	var total windowCounts
+	for _, bucket := range w.buckets {
+		if bucket.second > sec-int64(len(w.buckets)) {
+			total.requests += bucket.requests
+			total.marked += bucket.marked
+		}
+	}
+	return total
+}
+
+// seconds возвращает длину окна в секундах
+func (w *slidingWindow) seconds() int {
+	return len(w.buckets)
+}
+
+// reset обнуляет все корзины окна
+func (w *slidingWindow) reset() {
+	for i := range w.buckets {
+		w.buckets[i] = windowBucket{}
+	}
+}
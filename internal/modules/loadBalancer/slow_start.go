@@ -0,0 +1,106 @@
+package loadBalancer
+
+import (
+	modelsBackend "lb/internal/modules/backends/models"
+	"math/rand"
+	"sync"
+	"time"
+)
+
+// slowStartMinFraction - доля трафика, с которой начинается разогрев бэкенда
+const slowStartMinFraction = 0.1
+
+// slowStart отслеживает бэкенды, вернувшиеся в строй, и линейно наращивает
+// их эффективный вес от slowStartMinFraction до полного за заданное окно.
+// Первое появление бэкенда (старт приложения) разогревом не считается.
+type slowStart struct {
+	window time.Duration
+	mu     sync.RWMutex
+	since  map[uint64]time.Time // момент возвращения в строй
+	seen   map[uint64]struct{}  // бэкенды, уже бывшие здоровыми
+}
+
+// newSlowStart создает трекер разогрева или возвращает nil, если окно не задано
+func newSlowStart(window time.Duration) *slowStart {
+	if window <= 0 {
+		return nil
+	}
+	return &slowStart{
+		window: window,
+		since:  make(map[uint64]time.Time),
+		seen:   make(map[uint64]struct{}),
+	}
+}
+
+// markHealthy фиксирует переход бэкенда в здоровое состояние
+func (s *slowStart) markHealthy(id uint64) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if _, ok := s.seen[id]; !ok {
+		s.seen[id] = struct{}{}
+		return
+	}
+	s.since[id] = time.Now()
+}
+
+// factor возвращает текущую долю эффективного веса бэкенда в диапазоне (0, 1]
+func (s *slowStart) factor(id uint64) float64 {
+	s.mu.RLock()
+	since, ok := s.since[id]
+	s.mu.RUnlock()
+	if !ok {
+		return 1
+	}
+
+	elapsed := time.Since(since)
+	if elapsed >= s.window {
+		s.finish(id, since)
+		return 1
+	}
+	progress := float64(elapsed) / float64(s.window)
+	return slowStartMinFraction + (1-slowStartMinFraction)*progress
+}
+
+// finish снимает бэкенд с разогрева, если с момента чтения since
+// markHealthy не начал новый разогрев
+func (s *slowStart) finish(id uint64, since time.Time) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if current, ok := s.since[id]; ok && current.Equal(since) {
+		delete(s.since, id)
+	}
+}
+
+// filter убирает из кандидатов разогревающиеся бэкенды с вероятностью 1 - factor.
+// Так стратегии, не умеющие учитывать разогрев сами, вызываются один раз
+// и получают разогревающийся бэкенд пропорционально его доле веса.
+// Если отсеялись все кандидаты, возвращается исходный список.
+func (s *slowStart) filter(backends []*modelsBackend.Backend) []*modelsBackend.Backend {
+	var rest []*modelsBackend.Backend
+	for i, backend := range backends {
+		if factor := s.factor(backend.Id); factor < 1 && rand.Float64() >= factor {
+			if rest == nil {
+				rest = append(make([]*modelsBackend.Backend, 0, len(backends)), backends[:i]...)
+			}
+			continue
+		}
+		if rest != nil {
+			rest = append(rest, backend)
+		}
+	}
+	if len(rest) == 0 {
+		return backends
+	}
+	return rest
+}
+
+// without возвращает список бэкендов без указанного
+func without(backends []*modelsBackend.Backend, excluded *modelsBackend.Backend) []*modelsBackend.Backend {
+	rest := make([]*modelsBackend.Backend, 0, len(backends))
+	for _, backend := range backends {
+		if backend.Id != excluded.Id {
+			rest = append(rest, backend)
+		}
+	}
+	return rest
+}
@@ -0,0 +1,156 @@
+package loadBalancer
+
+import (
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/base64"
+	"go.uber.org/zap"
+	modelsBackend "lb/internal/modules/backends/models"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	defaultStickyCookie = "lb_affinity"
+	defaultStickyTTL    = time.Hour
+)
+
+// StickyConfig описывает настройки привязки клиента к бэкенду через cookie
+type StickyConfig struct {
+	Enabled    bool
+	CookieName string        // Имя cookie (по умолчанию lb_affinity); cookie действует только на пути маршрута
+	TTL        time.Duration // Время жизни привязки (по умолчанию 1 час)
+	SigningKey string        // Ключ HMAC-подписи; если пуст - генерируется при старте
+}
+
+// stickySessions выдает и проверяет подписанные cookie привязки.
+// Cookie хранит URL бэкенда, срок действия и HMAC-SHA256 подпись от них.
+// Path cookie совпадает с путем маршрута, поэтому маршруты с одинаковым
+// именем cookie не перезаписывают привязки друг друга.
+type stickySessions struct {
+	cookieName string
+	path       string
+	ttl        time.Duration
+	key        []byte
+}
+
+// newStickySessions создает менеджер привязок для маршрута path
+// или возвращает nil, если они выключены
+func newStickySessions(cfg StickyConfig, path string, logger *zap.Logger) *stickySessions {
+	if !cfg.Enabled {
+		return nil
+	}
+
+	s := &stickySessions{
+		cookieName: cfg.CookieName,
+		path:       path,
+		ttl:        cfg.TTL,
+		key:        []byte(cfg.SigningKey),
+	}
+	if s.cookieName == "" {
+		s.cookieName = defaultStickyCookie
+	}
+	if s.path == "" {
+		s.path = "/"
+	}
+	if s.ttl <= 0 {
+		s.ttl = defaultStickyTTL
+	}
+	if len(s.key) == 0 {
+		s.key = make([]byte, 32)
+		rand.Read(s.key)
+		logger.Warn("Sticky sessions signing key is not set, generated a random one; affinity is lost on restart",
+			zap.String("cookie", s.cookieName))
+	}
+	return s
+}
+
+// lookup возвращает бэкенд из cookie запроса, если подпись верна,
+// срок не истек и бэкенд все еще в списке здоровых
+func (s *stickySessions) lookup(r *http.Request, backends []*modelsBackend.Backend) *modelsBackend.Backend {
+	cookie, err := r.Cookie(s.cookieName)
+	if err != nil {
+		return nil
+	}
+	url, ok := s.verify(cookie.Value)
+	if !ok {
+		return nil
+	}
+	for _, backend := range backends {
+		if backend.URL == url {
+			return backend
+		}
+	}
+	return nil
+}
+
+// setCookie привязывает клиента к выбранному бэкенду.
+// Ранее выставленная в этом ответе привязка заменяется (запрос ушел на другой бэкенд при повторе).
+func (s *stickySessions) setCookie(w http.ResponseWriter, backend *modelsBackend.Backend) {
+	cookies := w.Header()["Set-Cookie"]
+	kept := cookies[:0]
+	for _, cookie := range cookies {
+		if !strings.HasPrefix(cookie, s.cookieName+"=") {
+			kept = append(kept, cookie)
+		}
+	}
+	if len(kept) != len(cookies) {
+		w.Header()["Set-Cookie"] = kept
+	}
+
+	expires := time.Now().Add(s.ttl)
+	http.SetCookie(w, &http.Cookie{
+		Name:     s.cookieName,
+		Value:    s.sign(backend.URL, expires),
+		Path:     s.path,
+		Expires:  expires,
+		MaxAge:   int(s.ttl.Seconds()),
+		HttpOnly: true,
+		SameSite: http.SameSiteLaxMode,
+	})
+}
+
+// sign формирует значение cookie: base64(url).expires.base64(hmac)
+func (s *stickySessions) sign(url string, expires time.Time) string {
+	payload := base64.RawURLEncoding.EncodeToString([]byte(url)) + "." + strconv.FormatInt(expires.Unix(), 10)
+	return payload + "." + base64.RawURLEncoding.EncodeToString(s.mac(payload))
+}
+
+// verify проверяет подпись и срок действия cookie и возвращает URL бэкенда
+func (s *stickySessions) verify(value string) (string, bool) {
+	idx := strings.LastIndexByte(value, '.')
+	if idx < 0 {
+		return "", false
+	}
+	payload, signature := value[:idx], value[idx+1:]
+
+	got, err := base64.RawURLEncoding.DecodeString(signature)
+	if err != nil || subtle.ConstantTimeCompare(got, s.mac(payload)) != 1 {
+		return "", false
+	}
+
+	encodedURL, expiresStr, ok := strings.Cut(payload, ".")
+	if !ok {
+		return "", false
+	}
+	expires, err := strconv.ParseInt(expiresStr, 10, 64)
+	if err != nil || time.Now().Unix() > expires {
+		return "", false
+	}
+	url, err := base64.RawURLEncoding.DecodeString(encodedURL)
+	if err != nil {
+		return "", false
+	}
+	return string(url), true
+}
+
+// mac вычисляет HMAC-SHA256 от полезной нагрузки cookie
+func (s *stickySessions) mac(payload string) []byte {
+	m := hmac.New(sha256.New, s.key)
+	m.Write([]byte(payload))
+	return m.Sum(nil)
+}
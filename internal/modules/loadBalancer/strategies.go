@@ -0,0 +1,110 @@
+package loadBalancer
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// StrategyFactory создает стратегию балансировки по конфигурации маршрута.
+// Возвращает ошибку, если опции алгоритма заданы неверно.
+type StrategyFactory func(route RouteConfig) (LoadBalancingStrategy, error)
+
+// defaultAlgorithm используется, когда в маршруте не указан algorithm
+const defaultAlgorithm = "round_robin"
+
+var (
+	strategiesMu sync.RWMutex
+	strategies   = map[string]StrategyFactory{
+		"round_robin": func(RouteConfig) (LoadBalancingStrategy, error) {
+			return NewRoundRobinStrategy(), nil
+		},
+		"weighted_round_robin": func(RouteConfig) (LoadBalancingStrategy, error) {
+			return NewWeightedRoundRobinStrategy(), nil
+		},
+		"least_connections": func(RouteConfig) (LoadBalancingStrategy, error) {
+			return NewLeastConnectionsStrategy(), nil
+		},
+		"p2c_ewma":        newP2CEWMAFromRoute,
+		"consistent_hash": newConsistentHashFromRoute,
+	}
+)
+
+// RegisterStrategy добавляет именованную фабрику стратегии в реестр.
+// Повторная регистрация с тем же именем заменяет фабрику.
+func RegisterStrategy(name string, factory StrategyFactory) {
+	strategiesMu.Lock()
+	defer strategiesMu.Unlock()
+	strategies[name] = factory
+}
+
+// NewStrategy создает стратегию, указанную в route.Algorithm, через реестр фабрик.
+// Неизвестное название алгоритма - ошибка со списком доступных вариантов.
+func NewStrategy(route RouteConfig) (LoadBalancingStrategy, error) {
+	name := route.Algorithm
+	if name == "" {
+		name = defaultAlgorithm
+	}
+
+	strategiesMu.RLock()
+	factory, ok := strategies[name]
+	strategiesMu.RUnlock()
+	if !ok {
+		return nil, fmt.Errorf("unknown balancing algorithm %q (available: %s)", name, strings.Join(StrategyNames(), ", "))
+	}
+
+	strategy, err := factory(route)
+	if err != nil {
+		return nil, fmt.Errorf("algorithm %q: %w", name, err)
+	}
+	return strategy, nil
+}
+
+// StrategyNames возвращает отсортированный список зарегистрированных алгоритмов
+func StrategyNames() []string {
+	strategiesMu.RLock()
+	defer strategiesMu.RUnlock()
+
+	names := make([]string, 0, len(strategies))
+	for name := range strategies {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// newP2CEWMAFromRoute читает опции decay и error_penalty
+func newP2CEWMAFromRoute(route RouteConfig) (LoadBalancingStrategy, error) {
+	strategy := NewP2CEWMAStrategy()
+	if v, ok := route.AlgorithmOptions["decay"]; ok {
+		decay, err := strconv.ParseFloat(v, 64)
+		if err != nil || decay <= 0 || decay > 1 {
+			return nil, fmt.Errorf("option decay must be a number in (0, 1], got %q", v)
+		}
+		strategy.decay = decay
+	}
+	if v, ok := route.AlgorithmOptions["error_penalty"]; ok {
+		penalty, err := time.ParseDuration(v)
+		if err != nil || penalty < 0 {
+			return nil, fmt.Errorf("option error_penalty must be a non-negative duration, got %q", v)
+		}
+		strategy.errorPenalty = penalty
+	}
+	return strategy, nil
+}
+
+// newConsistentHashFromRoute читает hash_key маршрута и опцию virtual_nodes
+func newConsistentHashFromRoute(route RouteConfig) (LoadBalancingStrategy, error) {
+	virtualNodes := 0
+	if v, ok := route.AlgorithmOptions["virtual_nodes"]; ok {
+		n, err := strconv.Atoi(v)
+		if err != nil || n <= 0 {
+			return nil, fmt.Errorf("option virtual_nodes must be a positive integer, got %q", v)
+		}
+		virtualNodes = n
+	}
+	return NewConsistentHashStrategy(route.HashKey, virtualNodes)
+}
@@ -0,0 +1,153 @@
+package loadBalancer
+
+import (
+	"context"
+	"io"
+	"mime"
+	"net/http"
+	"sync"
+	"time"
+)
+
+const (
+	// defaultBackendTimeout ограничивает попытку целиком: ожидание заголовков и чтение тела
+	defaultBackendTimeout = 10 * time.Second
+	// defaultStreamIdleTimeout - сколько потоковый ответ может молчать, прежде чем его оборвут
+	defaultStreamIdleTimeout = 60 * time.Second
+)
+
+// StreamingConfig задает поведение для потоковых ответов (SSE, chunked)
+type StreamingConfig struct {
+	FlushInterval time.Duration // Период сброса буфера для обычных ответов (0 - без периодического сброса)
+	IdleTimeout   time.Duration // Таймаут простоя потокового ответа (по умолчанию 60s)
+}
+
+// idleSwitcher - тело ответа, которое умеет переходить на таймаут простоя
+// (timedBody и обертки над ним)
+type idleSwitcher interface {
+	switchToIdle(idle time.Duration)
+}
+
+// timedBody - тело ответа бэкенда, чтение которого ограничено таймаутом.
+// По умолчанию действует общий дедлайн попытки; для потоковых ответов
+// он заменяется таймаутом простоя, который продлевается при каждом чтении.
+type timedBody struct {
+	io.ReadCloser
+	timer  *time.Timer
+	cancel context.CancelFunc
+	mu     sync.Mutex
+	idle   time.Duration
+}
+
+// Read продлевает таймаут простоя, если тело переведено в потоковый режим
+func (b *timedBody) Read(p []byte) (int, error) {
+	n, err := b.ReadCloser.Read(p)
+	if n > 0 {
+		b.mu.Lock()
+		if b.idle > 0 {
+			b.timer.Reset(b.idle)
+		}
+		b.mu.Unlock()
+	}
+	return n, err
+}
+
+// Close останавливает таймер и освобождает контекст попытки
+func (b *timedBody) Close() error {
+	b.timer.Stop()
+	err := b.ReadCloser.Close()
+	b.cancel()
+	return err
+}
+
+// switchToIdle заменяет общий дедлайн таймаутом простоя
+func (b *timedBody) switchToIdle(idle time.Duration) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.idle = idle
+	b.timer.Reset(idle)
+}
+
+// doWithTimeout выполняет одну попытку запроса с общим дедлайном timeout.
+// Контекст попытки живет, пока не закрыто тело ответа.
+func doWithTimeout(ctx context.Context, client *http.Client, req *http.Request, timeout time.Duration) (*http.Response, error) {
+	attemptCtx, cancel := context.WithCancel(ctx)
+	timer := time.AfterFunc(timeout, cancel)
+
+	resp, err := client.Do(req.WithContext(attemptCtx))
+	if err != nil {
+		timer.Stop()
+		cancel()
+		return nil, err
+	}
+	resp.Body = &timedBody{ReadCloser: resp.Body, timer: timer, cancel: cancel}
+	return resp, nil
+}
+
+// isStreamingResponse определяет ответы, которые нужно отдавать клиенту без задержек:
+// Server-Sent Events и ответы без известной длины
+func isStreamingResponse(resp *http.Response) bool {
+	if mediaType, _, err := mime.ParseMediaType(resp.Header.Get("Content-Type")); err == nil && mediaType == "text/event-stream" {
+		return true
+	}
+	return resp.ContentLength == -1
+}
+
+// flushWriter сбрасывает данные клиенту после каждой записи (interval <= 0)
+// или не позже чем через interval после первой несброшенной записи
+type flushWriter struct {
+	w        io.Writer
+	rc       *http.ResponseController
+	interval time.Duration
+
+	mu      sync.Mutex
+	timer   *time.Timer
+	pending bool
+}
+
+func newFlushWriter(w http.ResponseWriter, interval time.Duration) *flushWriter {
+	return &flushWriter{w: w, rc: http.NewResponseController(w), interval: interval}
+}
+
+func (fw *flushWriter) Write(p []byte) (int, error) {
+	fw.mu.Lock()
+	defer fw.mu.Unlock()
+
+	n, err := fw.w.Write(p)
+	if err != nil {
+		return n, err
+	}
+	if fw.interval <= 0 {
+		fw.rc.Flush()
+		return n, nil
+	}
+	if !fw.pending {
+		fw.pending = true
+		if fw.timer == nil {
+			fw.timer = time.AfterFunc(fw.interval, fw.delayedFlush)
+		} else {
+			fw.timer.Reset(fw.interval)
+		}
+	}
+	return n, nil
+}
+
+// delayedFlush срабатывает по таймеру и сбрасывает накопленные данные
+func (fw *flushWriter) delayedFlush() {
+	fw.mu.Lock()
+	defer fw.mu.Unlock()
+	if fw.pending {
+		fw.rc.Flush()
+		fw.pending = false
+	}
+}
+
+// stop отменяет отложенный сброс; вызывается после копирования тела
+func (fw *flushWriter) stop() {
+	fw.mu.Lock()
+	defer fw.mu.Unlock()
+	fw.pending = false
+	if fw.timer != nil {
+		fw.timer.Stop()
+	}
+}
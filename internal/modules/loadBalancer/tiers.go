@@ -0,0 +1,105 @@
+package loadBalancer
+
+import (
+	modelsBackend "lb/internal/modules/backends/models"
+	"sort"
+)
+
+// BackendGroup описывает группу бэкендов маршрута с приоритетом.
+// Трафик идет в группу с наименьшим значением Priority, пока в ней
+// не меньше MinHealthy здоровых бэкендов, иначе переключается на следующую.
+type BackendGroup struct {
+	Name       string
+	Priority   int
+	MinHealthy int // По умолчанию 1
+}
+
+// GroupStatus - состояние одной группы для admin API
+type GroupStatus struct {
+	Name       string `json:"name"`
+	Priority   int    `json:"priority"`
+	MinHealthy int    `json:"min_healthy"`
+	Healthy    int    `json:"healthy"`
+	Total      int    `json:"total"`
+	Active     bool   `json:"active"`
+}
+
+// TierStatus - состояние уровней приоритета маршрута для admin API
+type TierStatus struct {
+	ActiveGroup string        `json:"active_group"`
+	Groups      []GroupStatus `json:"groups"`
+}
+
+// tierSet хранит группы маршрута, упорядоченные по приоритету
+type tierSet struct {
+	groups []BackendGroup
+	sizes  map[string]int // число бэкендов в группе по конфигурации
+}
+
+// newTierSet строит набор уровней или возвращает nil, если группы не заданы
+func newTierSet(groups []BackendGroup, backends []modelsBackend.Backend) *tierSet {
+	if len(groups) == 0 {
+		return nil
+	}
+
+	sorted := make([]BackendGroup, len(groups))
+	copy(sorted, groups)
+	for i := range sorted {
+		if sorted[i].MinHealthy <= 0 {
+			sorted[i].MinHealthy = 1
+		}
+	}
+	sort.SliceStable(sorted, func(i, j int) bool { return sorted[i].Priority < sorted[j].Priority })
+
+	sizes := make(map[string]int, len(sorted))
+	for _, backend := range backends {
+		sizes[backend.Group]++
+	}
+	return &tierSet{groups: sorted, sizes: sizes}
+}
+
+// selectActive возвращает имя активной группы и ее здоровые бэкенды.
+// Если ни одна группа не набирает MinHealthy, берется самая приоритетная
+// группа, в которой есть хоть один здоровый бэкенд.
+func (t *tierSet) selectActive(healthy []*modelsBackend.Backend) (string, []*modelsBackend.Backend) {
+	byGroup := groupBackends(healthy)
+
+	for _, group := range t.groups {
+		if len(byGroup[group.Name]) >= group.MinHealthy {
+			return group.Name, byGroup[group.Name]
+		}
+	}
+	for _, group := range t.groups {
+		if len(byGroup[group.Name]) > 0 {
+			return group.Name, byGroup[group.Name]
+		}
+	}
+	return "", nil
+}
+
+// status собирает состояние всех групп для admin API
+func (t *tierSet) status(active string, healthy []*modelsBackend.Backend) TierStatus {
+	byGroup := groupBackends(healthy)
+
+	status := TierStatus{ActiveGroup: active, Groups: make([]GroupStatus, 0, len(t.groups))}
+	for _, group := range t.groups {
+		status.Groups = append(status.Groups, GroupStatus{
+			Name:       group.Name,
+			Priority:   group.Priority,
+			MinHealthy: group.MinHealthy,
+			Healthy:    len(byGroup[group.Name]),
+			Total:      t.sizes[group.Name],
+			Active:     group.Name == active,
+		})
+	}
+	return status
+}
+
+// groupBackends раскладывает бэкенды по именам групп
+func groupBackends(backends []*modelsBackend.Backend) map[string][]*modelsBackend.Backend {
+	byGroup := make(map[string][]*modelsBackend.Backend)
+	for _, backend := range backends {
+		byGroup[backend.Group] = append(byGroup[backend.Group], backend)
+	}
+	return byGroup
+}
@@ -0,0 +1,159 @@
+package loadBalancer
+
+import (
+	"fmt"
+	modelsBackend "lb/internal/modules/backends/models"
+	"math/rand"
+	"net/http"
+	"sync"
+	"sync/atomic"
+)
+
+// BackendPool описывает именованный пул бэкендов маршрута (например, stable и canary)
+// и долю трафика в процентах, которая на него направляется
+type BackendPool struct {
+	Name    string
+	Percent int
+}
+
+// PoolOverride задает заголовок и/или cookie, значение которых принудительно выбирает пул
+type PoolOverride struct {
+	Header string
+	Cookie string
+}
+
+// PoolStatus - состояние пула для admin API
+type PoolStatus struct {
+	Name     string `json:"name"`
+	Percent  int    `json:"percent"`
+	Requests uint64 `json:"requests"`
+}
+
+// trafficSplit распределяет запросы между пулами бэкендов по процентам.
+// Проценты можно менять во время работы через admin API.
+type trafficSplit struct {
+	mu       sync.RWMutex
+	pools    []BackendPool
+	override PoolOverride
+	requests map[string]*uint64 // число запросов по имени пула
+}
+
+// newTrafficSplit создает распределитель трафика или возвращает nil, если пулы не заданы
+func newTrafficSplit(pools []BackendPool, override PoolOverride) *trafficSplit {
+	if len(pools) == 0 {
+		return nil
+	}
+	ts := &trafficSplit{
+		pools:    append([]BackendPool(nil), pools...),
+		override: override,
+		requests: make(map[string]*uint64, len(pools)),
+	}
+	for _, pool := range pools {
+		ts.requests[pool.Name] = new(uint64)
+	}
+	return ts
+}
+
+// choosePool возвращает пул для запроса. forced=true, если пул задан
+// заголовком или cookie переопределения (например, QA закрепляется на canary).
+func (ts *trafficSplit) choosePool(r *http.Request) (pool string, forced bool) {
+	if name := ts.overrideValue(r); name != "" {
+		if _, ok := ts.requests[name]; ok {
+			return name, true
+		}
+	}
+
+	ts.mu.RLock()
+	defer ts.mu.RUnlock()
+
+	n := rand.Intn(100)
+	for _, p := range ts.pools {
+		if n < p.Percent {
+			return p.Name, false
+		}
+		n -= p.Percent
+	}
+	return ts.pools[len(ts.pools)-1].Name, false
+}
+
+// overrideValue достает имя пула из заголовка или cookie переопределения
+func (ts *trafficSplit) overrideValue(r *http.Request) string {
+	if ts.override.Header != "" {
+		if v := r.Header.Get(ts.override.Header); v != "" {
+			return v
+		}
+	}
+	if ts.override.Cookie != "" {
+		if c, err := r.Cookie(ts.override.Cookie); err == nil {
+			return c.Value
+		}
+	}
+	return ""
+}
+
+// record увеличивает счетчик запросов пула
+func (ts *trafficSplit) record(pool string) {
+	if counter, ok := ts.requests[pool]; ok {
+		atomic.AddUint64(counter, 1)
+	}
+}
+
+// setPercents атомарно заменяет проценты пулов.
+// Должны быть указаны все пулы маршрута, а сумма - равняться 100.
+func (ts *trafficSplit) setPercents(percents map[string]int) error {
+	if len(percents) != len(ts.requests) {
+		return fmt.Errorf("split must list all %d pools", len(ts.requests))
+	}
+	total := 0
+	for name, percent := range percents {
+		if _, ok := ts.requests[name]; !ok {
+			return fmt.Errorf("unknown pool %q", name)
+		}
+		if percent < 0 {
+			return fmt.Errorf("pool %q: percent must not be negative", name)
+		}
+		total += percent
+	}
+	if total != 100 {
+		return fmt.Errorf("pool percents must sum to 100, got %d", total)
+	}
+
+	ts.mu.Lock()
+	defer ts.mu.Unlock()
+	for i := range ts.pools {
+		ts.pools[i].Percent = percents[ts.pools[i].Name]
+	}
+	return nil
+}
+
+// status возвращает текущие проценты и счетчики запросов пулов
+func (ts *trafficSplit) status() []PoolStatus {
+	ts.mu.RLock()
+	defer ts.mu.RUnlock()
+
+	result := make([]PoolStatus, 0, len(ts.pools))
+	for _, pool := range ts.pools {
+		result = append(result, PoolStatus{
+			Name:     pool.Name,
+			Percent:  pool.Percent,
+			Requests: atomic.LoadUint64(ts.requests[pool.Name]),
+		})
+	}
+	return result
+}
+
+// poolBackends оставляет только бэкенды указанного пула.
+// Если в пуле нет здоровых бэкендов, возвращается исходный список,
+// чтобы падение canary не превращалось в ошибки для пользователей.
+func poolBackends(backends []*modelsBackend.Backend, pool string) []*modelsBackend.Backend {
+	filtered := make([]*modelsBackend.Backend, 0, len(backends))
+	for _, backend := range backends {
+		if backend.Pool == pool {
+			filtered = append(filtered, backend)
+		}
+	}
+	if len(filtered) == 0 {
+		return backends
+	}
+	return filtered
+}
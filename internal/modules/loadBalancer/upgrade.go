@@ -0,0 +1,219 @@
+package loadBalancer
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"go.uber.org/zap"
+	"io"
+	"lb/internal/modules/backends"
+	"lb/internal/modules/backends/models"
+	"net"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+)
+
+// upgradeTracker учитывает клиентские соединения, переключенные на другой протокол,
+// чтобы их можно было дождаться и закрыть при остановке, и считает открытые туннели
+// по бэкендам независимо от стратегии балансировки
+type upgradeTracker struct {
+	mu        sync.Mutex
+	conns     map[net.Conn]struct{}
+	byBackend map[uint64]int // открытые туннели по Id бэкенда
+	wg        sync.WaitGroup
+	draining  bool
+}
+
+func newUpgradeTracker() *upgradeTracker {
+	return &upgradeTracker{
+		conns:     make(map[net.Conn]struct{}),
+		byBackend: make(map[uint64]int),
+	}
+}
+
+// add регистрирует туннель к бэкенду backendId; во время остановки новые соединения не принимаются
+func (t *upgradeTracker) add(backendId uint64, conns ...net.Conn) bool {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if t.draining {
+		return false
+	}
+	for _, c := range conns {
+		t.conns[c] = struct{}{}
+	}
+	t.byBackend[backendId]++
+	t.wg.Add(1)
+	return true
+}
+
+// remove снимает туннель с учета после его завершения
+func (t *upgradeTracker) remove(backendId uint64, conns ...net.Conn) {
+	t.mu.Lock()
+	for _, c := range conns {
+		delete(t.conns, c)
+	}
+	if t.byBackend[backendId]--; t.byBackend[backendId] <= 0 {
+		delete(t.byBackend, backendId)
+	}
+	t.mu.Unlock()
+	t.wg.Done()
+}
+
+// active возвращает число открытых туннелей к бэкенду
+func (t *upgradeTracker) active(backendId uint64) int {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	return t.byBackend[backendId]
+}
+
+// drain ждет завершения туннелей до отмены ctx, затем закрывает оставшиеся соединения
+func (t *upgradeTracker) drain(ctx context.Context) int {
+	t.mu.Lock()
+	t.draining = true
+	t.mu.Unlock()
+
+	done := make(chan struct{})
+	go func() {
+		t.wg.Wait()
+		close(done)
+	}()
+
+	select {
+	case <-done:
+		return 0
+	case <-ctx.Done():
+	}
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	for c := range t.conns {
+		c.Close()
+	}
+	return len(t.conns)
+}
+
+// isUpgradeRequest определяет запрос на смену протокола (Connection: Upgrade + Upgrade: ...)
+func isUpgradeRequest(r *http.Request) bool {
+	if r.Header.Get("Upgrade") == "" {
+		return false
+	}
+	for _, value := range r.Header.Values("Connection") {
+		for _, token := range strings.Split(value, ",") {
+			if strings.EqualFold(strings.TrimSpace(token), "upgrade") {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// newUpgradeTransports создает HTTP/1.1-транспорты для Upgrade-запросов по Id бэкенда
+func newUpgradeTransports(backendsList []models.Backend) (map[uint64]*http.Transport, error) {
+	transports := make(map[uint64]*http.Transport, len(backendsList))
+	for _, backend := range backendsList {
+		transport, err := backends.NewUpgradeTransport(backend)
+		if err != nil {
+			return nil, err
+		}
+		transports[backend.Id] = transport
+	}
+	return transports, nil
+}
+
+// proxyUpgrade проксирует запрос на смену протокола: отправляет его бэкенду по HTTP/1.1,
+// при ответе 101 перехватывает клиентское соединение и перекачивает байты
+// в обе стороны, пока одна из сторон не закроет соединение
+func (h *LoadBalancerHandler) proxyUpgrade(w http.ResponseWriter, r *http.Request, backend *models.Backend, startTime time.Time) {
+	if tracker, ok := h.lb.Algorithm.(RequestTracker); ok {
+		tracker.RequestStarted(backend)
+		defer tracker.RequestFinished(backend)
+	}
+
+	transport, ok := h.upgradeTransports[backend.Id]
+	if !ok {
+		transport, _ = backends.NewUpgradeTransport(models.Backend{URL: backend.URL})
+	}
+
+	req, err := http.NewRequestWithContext(r.Context(), r.Method, buildTargetURL(backend.URL, r.URL.Path, r.URL.RawQuery), nil)
+	if err != nil {
+		h.handleError(w, r, err, http.StatusInternalServerError, startTime)
+		return
+	}
+	req.Header = r.Header.Clone()
+	// Connection и Upgrade - hop-by-hop, но для смены протокола их нужно передать бэкенду
+	h.headers.apply(req, r)
+	req.Header.Set("Connection", "Upgrade")
+	req.Header.Set("Upgrade", r.Header.Get("Upgrade"))
+
+	resp, err := transport.RoundTrip(req)
+	if err != nil {
+		h.handleError(w, r, err, http.StatusBadGateway, startTime)
+		return
+	}
+
+	// Бэкенд отказался переключать протокол - отдаем его ответ как обычный
+	if resp.StatusCode != http.StatusSwitchingProtocols {
+		defer resp.Body.Close()
+		h.copyResponse(w, resp)
+		return
+	}
+
+	backendConn, ok := resp.Body.(io.ReadWriteCloser)
+	if !ok {
+		resp.Body.Close()
+		h.handleError(w, r, errors.New("backend connection does not support protocol switch"), http.StatusBadGateway, startTime)
+		return
+	}
+	defer backendConn.Close()
+
+	hijacker, ok := w.(http.Hijacker)
+	if !ok {
+		h.handleError(w, r, errors.New("client connection does not support protocol switch"), http.StatusInternalServerError, startTime)
+		return
+	}
+	clientConn, clientBuf, err := hijacker.Hijack()
+	if err != nil {
+		h.handleError(w, r, err, http.StatusInternalServerError, startTime)
+		return
+	}
+	defer clientConn.Close()
+
+	if !h.upgrades.add(backend.Id, clientConn) {
+		h.logger.Debug("Rejecting upgrade during shutdown", zap.String("path", r.URL.Path))
+		return
+	}
+	defer h.upgrades.remove(backend.Id, clientConn)
+
+	// Передаем клиенту ответ 101 с заголовками бэкенда
+	fmt.Fprintf(clientBuf, "HTTP/1.1 %s\r\n", resp.Status)
+	resp.Header.Write(clientBuf)
+	clientBuf.WriteString("\r\n")
+	if err := clientBuf.Flush(); err != nil {
+		h.logger.Debug("Failed to send switching protocols response", zap.Error(err))
+		return
+	}
+
+	h.logger.Debug("Connection upgraded",
+		zap.String("backend", backend.URL),
+		zap.String("protocol", resp.Header.Get("Upgrade")),
+	)
+
+	// Каждое направление копируется своей горутиной; завершение любого закрывает туннель
+	errc := make(chan error, 2)
+	go func() {
+		_, err := io.Copy(backendConn, clientBuf) // clientBuf отдает и уже буферизованные байты
+		errc <- err
+	}()
+	go func() {
+		_, err := io.Copy(clientConn, backendConn)
+		errc <- err
+	}()
+	<-errc
+
+	h.logger.Debug("Upgraded connection closed",
+		zap.String("backend", backend.URL),
+		zap.Duration("duration", time.Since(startTime)),
+	)
+}
@@ -0,0 +1,88 @@
+package loadBalancer
+
+import (
+	"errors"
+	modelsBackend "lb/internal/modules/backends/models"
+	"sync"
+)
+
+// ------------------WEIGHTED ROUND-ROBIN ------------------
+// WeightedRoundRobinAlgorithm реализует плавный взвешенный round robin (алгоритм nginx).
+// Бэкенды с большим весом получают пропорционально больше запросов,
+// при этом запросы к ним перемежаются с остальными, а не идут пачкой.
+// Текущие веса хранятся отдельно для каждого набора кандидатов, поэтому запросы
+// к подмножеству бэкендов не сбивают чередование на полном наборе.
+type WeightedRoundRobinAlgorithm struct {
+	mu      sync.Mutex
+	weights map[string]map[uint64]float64        // текущий вес по отпечатку набора и Id бэкенда
+	sets    recentSets                           // порядок использования наборов
+	scale   func(*modelsBackend.Backend) float64 // доля веса (slow start), nil - полный вес
+}
+
+func NewWeightedRoundRobinStrategy() *WeightedRoundRobinAlgorithm {
+	return &WeightedRoundRobinAlgorithm{
+		weights: make(map[string]map[uint64]float64),
+	}
+}
+
+// SetWeightScale задает долю веса бэкенда, например, для разогрева после возвращения в строй.
+// Вызывается при создании маршрута, до начала балансировки.
+func (wrr *WeightedRoundRobinAlgorithm) SetWeightScale(scale func(*modelsBackend.Backend) float64) {
+	wrr.mu.Lock()
+	defer wrr.mu.Unlock()
+	wrr.scale = scale
+}
+
+// GetNextBackend выбирает бэкенд с максимальным текущим весом.
+// На каждом шаге текущий вес каждого бэкенда увеличивается на его эффективный вес,
+// а у выбранного уменьшается на сумму всех весов.
+func (wrr *WeightedRoundRobinAlgorithm) GetNextBackend(backends []*modelsBackend.Backend) (*modelsBackend.Backend, error) {
+	if len(backends) == 0 {
+		return nil, errors.New("no backends available")
+	}
+
+	wrr.mu.Lock()
+	defer wrr.mu.Unlock()
+
+	currentWeight := wrr.setWeights(backends)
+	var best *modelsBackend.Backend
+	total := 0.0
+	for _, backend := range backends {
+		weight := float64(effectiveWeight(backend))
+		if wrr.scale != nil {
+			weight *= wrr.scale(backend)
+		}
+		total += weight
+		currentWeight[backend.Id] += weight
+		if best == nil || currentWeight[backend.Id] > currentWeight[best.Id] {
+			best = backend
+		}
+	}
+
+	currentWeight[best.Id] -= total
+	return best, nil
+}
+
+// setWeights возвращает текущие веса набора кандидатов, заводя их для нового набора.
+// Вес копится только у кандидатов набора, поэтому бэкенд, вернувшийся в строй,
+// не получает всплеска трафика. Состояние давно не встречавшихся наборов удаляется.
+func (wrr *WeightedRoundRobinAlgorithm) setWeights(backends []*modelsBackend.Backend) map[uint64]float64 {
+	signature := backendsSignature(backends)
+	if evicted, ok := wrr.sets.touch(signature); ok {
+		delete(wrr.weights, evicted)
+	}
+	current, ok := wrr.weights[signature]
+	if !ok {
+		current = make(map[uint64]float64, len(backends))
+		wrr.weights[signature] = current
+	}
+	return current
+}
+
+// effectiveWeight возвращает вес бэкенда, считая незаданный вес равным 1
+func effectiveWeight(backend *modelsBackend.Backend) int {
+	if backend.Weight <= 0 {
+		return 1
+	}
+	return backend.Weight
+}
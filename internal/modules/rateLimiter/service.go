@@ -49,7 +49,8 @@ func NewTokenBucketLimiter(ctx context.Context, limit int, period time.Duration,
 	return tb
 }
 
-// StartPeriod запускает периодическое пополнение токенов
+// StartPeriod запускает периодическое пополнение токенов до отмены ctx.
+// Вызывается из NewTokenBucketLimiter; повторный вызов удваивает скорость пополнения.
 func (tb *TokenBucketLimiter) StartPeriod(ctx context.Context) {
 	tb.logger.Info("Token bucket refill started", zap.Duration("period", tb.Period))
 	ticker := time.NewTicker(tb.Period)
@@ -1,8 +1,11 @@
 package routes
 
 import (
+	"context"
 	"encoding/json"
 	"go.uber.org/zap"
+	"lb/internal/modules/backends"
+	"lb/internal/modules/healthchecker"
 	"lb/internal/modules/loadBalancer"
 	rateLimiter2 "lb/internal/modules/rateLimiter"
 	"net"
@@ -10,9 +13,14 @@ import (
 )
 
 // CreateRouter инициализирует маршрутизатор с обработчиками балансировщика нагрузки
-// и middleware для ограничения запросов. Также добавляет endpoint для мониторинга клиентов.
-func CreateRouter(lbMap map[string]*loadBalancer.LoadBalancerHandler,
-	limiter *rateLimiter2.TokenBucketLimiter, logger *zap.Logger) *http.ServeMux {
+// и middleware для ограничения запросов. Также добавляет endpoint для мониторинга клиентов
+// и admin API; registry и healthChecker нужны для состояния бэкендов.
+// adminToken - bearer-токен admin API; без него admin API доступен только на чтение.
+// shutdown отменяется при остановке сервера и завершает долгие потоки admin API,
+// которые http.Server.Shutdown не прерывает сам.
+func CreateRouter(shutdown context.Context, lbMap map[string]*loadBalancer.LoadBalancerHandler,
+	limiter *rateLimiter2.TokenBucketLimiter, registry *backends.BackendRegistry,
+	healthChecker *healthchecker.HealthChecker, adminToken string, logger *zap.Logger) *http.ServeMux {
 
 	router := http.NewServeMux()
 
@@ -25,6 +33,15 @@ func CreateRouter(lbMap map[string]*loadBalancer.LoadBalancerHandler,
 	// Специальный endpoint для получения списка клиентов
 	router.HandleFunc("/clients", limiter.ClientsHandler)
 
+	// Admin API для наблюдения за состоянием балансировщика
+	router.HandleFunc("/admin/tiers", adminAuth(adminToken, tiersHandler(lbMap, logger)))
+	router.HandleFunc("/admin/splits", adminAuth(adminToken, splitsHandler(lbMap, logger)))
+	router.HandleFunc("/admin/retries", adminAuth(adminToken, retriesHandler(lbMap, logger)))
+	router.HandleFunc("/admin/breakers", adminAuth(adminToken, breakersHandler(lbMap, logger)))
+	router.HandleFunc("/admin/mirrors", adminAuth(adminToken, mirrorsHandler(lbMap, logger)))
+	router.HandleFunc("/admin/backends", adminAuth(adminToken, backendsHandler(lbMap, registry, healthChecker, logger)))
+	router.HandleFunc("/admin/backends/events", adminAuth(adminToken, healthEventsHandler(shutdown, lbMap, registry, logger)))
+
 	return router
 }
 
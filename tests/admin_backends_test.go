@@ -0,0 +1,197 @@
+package integration
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+	"io"
+	routes "lb/internal/modules"
+	"lb/internal/modules/backends"
+	"lb/internal/modules/backends/models"
+	"lb/internal/modules/healthchecker"
+	"lb/internal/modules/loadBalancer"
+	"lb/internal/modules/rateLimiter"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+// adminBackend - бэкенд в ответе /admin/backends
+type adminBackend struct {
+	BackendId            uint64    `json:"backend_id"`
+	Healthy              bool      `json:"healthy"`
+	CheckHealthy         bool      `json:"check_healthy"`
+	Ejected              bool      `json:"ejected"`
+	LastCheck            time.Time `json:"last_check"`
+	LastError            string    `json:"last_error"`
+	ConsecutiveFailures  int       `json:"consecutive_failures"`
+	ConsecutiveSuccesses int       `json:"consecutive_successes"`
+}
+
+// adminHealthEvent - событие потока /admin/backends/events
+type adminHealthEvent struct {
+	BackendId uint64 `json:"backend_id"`
+	URL       string `json:"url"`
+	Route     string `json:"route"`
+	Healthy   bool   `json:"healthy"`
+	Source    string `json:"source"`
+}
+
+// fetchAdminBackends возвращает бэкенды маршрутов по Id
+func fetchAdminBackends(t *testing.T, serverURL string) map[uint64]adminBackend {
+	resp, err := http.Get(serverURL + "/admin/backends")
+	require.NoError(t, err)
+	defer resp.Body.Close()
+
+	var result []struct {
+		Path     string         `json:"path"`
+		Backends []adminBackend `json:"backends"`
+	}
+	require.NoError(t, json.NewDecoder(resp.Body).Decode(&result))
+	byId := make(map[uint64]adminBackend)
+	for _, route := range result {
+		for _, backend := range route.Backends {
+			byId[backend.BackendId] = backend
+		}
+	}
+	return byId
+}
+
+func TestAdminBackendsAndHealthEvents(t *testing.T) {
+	up := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	defer up.Close()
+	down := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusInternalServerError)
+	}))
+	defer down.Close()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	logger := zap.NewNop()
+	registry := backends.NewBackendRegistry()
+	hc := healthchecker.NewHealthChecker(20*time.Millisecond, 20*time.Millisecond, registry, &http.Client{Timeout: 5 * time.Second}, logger)
+	lbMap, err := loadBalancer.CreateLoadBalancers([]loadBalancer.RouteConfig{{
+		Path: "/api",
+		Backends: []models.Backend{
+			{Id: 9921, URL: up.URL, Health: "/health"},
+			{Id: 9922, URL: down.URL, Health: "/health"},
+		},
+	}}, registry, hc, logger)
+	require.NoError(t, err)
+	limiter := rateLimiter.NewTokenBucketLimiter(ctx, 100, 30*time.Second, logger)
+	server := httptest.NewServer(routes.CreateRouter(ctx, lbMap, limiter, registry, hc, "", logger))
+	defer server.Close()
+
+	// Подписываемся на поток до запуска проверок, чтобы не пропустить первый переход
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, server.URL+"/admin/backends/events", nil)
+	require.NoError(t, err)
+	stream, err := http.DefaultClient.Do(req)
+	require.NoError(t, err)
+	defer stream.Body.Close()
+	assert.Equal(t, "text/event-stream", stream.Header.Get("Content-Type"))
+
+	events := make(chan adminHealthEvent, 10)
+	go func() {
+		scanner := bufio.NewScanner(stream.Body)
+		for scanner.Scan() {
+			if data, ok := strings.CutPrefix(scanner.Text(), "data: "); ok {
+				var event adminHealthEvent
+				if json.Unmarshal([]byte(data), &event) == nil {
+					events <- event
+				}
+			}
+		}
+	}()
+	nextEvent := func() adminHealthEvent {
+		select {
+		case event := <-events:
+			return event
+		case <-time.After(time.Second):
+			t.Fatal("no health event received")
+			return adminHealthEvent{}
+		}
+	}
+
+	hc.Start()
+	event := nextEvent()
+	assert.Equal(t, adminHealthEvent{BackendId: 9921, URL: up.URL, Route: "/api", Healthy: true, Source: "health_check"}, event)
+
+	require.Eventually(t, func() bool {
+		return fetchAdminBackends(t, server.URL)[9922].ConsecutiveFailures >= 2
+	}, time.Second, 20*time.Millisecond)
+	status := fetchAdminBackends(t, server.URL)
+	assert.True(t, status[9921].Healthy)
+	assert.False(t, status[9921].LastCheck.IsZero())
+	assert.Positive(t, status[9921].ConsecutiveSuccesses)
+	assert.Empty(t, status[9921].LastError)
+	assert.False(t, status[9922].Healthy)
+	assert.Zero(t, status[9922].ConsecutiveSuccesses)
+	assert.Contains(t, status[9922].LastError, "unexpected status 500")
+
+	// Исключение пассивной проверкой видно и в потоке, и в состоянии бэкенда
+	registry.UpdateHealth(models.BackendStatus{Id: 9921, IsHealthy: false, Source: models.SourceOutlier})
+	event = nextEvent()
+	assert.Equal(t, adminHealthEvent{BackendId: 9921, URL: up.URL, Route: "/api", Healthy: false, Source: "outlier"}, event)
+	status = fetchAdminBackends(t, server.URL)
+	assert.False(t, status[9921].Healthy)
+	assert.True(t, status[9921].CheckHealthy)
+	assert.True(t, status[9921].Ejected)
+}
+
+func TestHealthWatcherCountsDroppedEvents(t *testing.T) {
+	registry := backends.NewBackendRegistry()
+	watcher := registry.Watch()
+	defer watcher.Stop()
+
+	// Наблюдатель не читает события: после заполнения буфера они теряются,
+	// но UpdateHealth не блокируется
+	const transitions = 100
+	for i := 0; i < transitions; i++ {
+		require.NoError(t, registry.UpdateHealth(models.BackendStatus{Id: 9931, IsHealthy: i%2 == 0}))
+	}
+	buffered := len(watcher.Updates())
+	assert.Positive(t, buffered)
+	assert.Equal(t, uint64(transitions-buffered), watcher.TakeDropped())
+	assert.Zero(t, watcher.TakeDropped())
+}
+
+func TestHealthEventsStreamEndsOnShutdown(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	logger := zap.NewNop()
+	registry := backends.NewBackendRegistry()
+	hc := healthchecker.NewHealthChecker(time.Minute, time.Minute, registry, http.DefaultClient, logger)
+	limiter := rateLimiter.NewTokenBucketLimiter(ctx, 100, 30*time.Second, logger)
+
+	streamsCtx, stopStreams := context.WithCancel(ctx)
+	server := httptest.NewUnstartedServer(routes.CreateRouter(streamsCtx, map[string]*loadBalancer.LoadBalancerHandler{}, limiter, registry, hc, "", logger))
+	server.Config.RegisterOnShutdown(stopStreams)
+	server.Start()
+	defer server.Close()
+
+	stream, err := http.Get(server.URL + "/admin/backends/events")
+	require.NoError(t, err)
+	defer stream.Body.Close()
+	ended := make(chan struct{})
+	go func() {
+		io.Copy(io.Discard, stream.Body)
+		close(ended)
+	}()
+
+	// Открытый поток не задерживает остановку сервера
+	shutdownCtx, cancelShutdown := context.WithTimeout(ctx, 5*time.Second)
+	defer cancelShutdown()
+	start := time.Now()
+	require.NoError(t, server.Config.Shutdown(shutdownCtx))
+	assert.Less(t, time.Since(start), 2*time.Second)
+	select {
+	case <-ended:
+	case <-time.After(time.Second):
+		t.Fatal("health event stream was not closed on shutdown")
+	}
+}
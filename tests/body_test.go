@@ -0,0 +1,116 @@
+package integration
+
+import (
+	"bytes"
+	"crypto/rand"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"io"
+	"lb/internal/modules/loadBalancer"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// receivedBody - тело и Content-Length, полученные бэкендом в одной попытке
+type receivedBody struct {
+	body          []byte
+	contentLength int64
+}
+
+// newBodyRoute поднимает маршрут из двух бэкендов, записывающих полученные тела.
+// Первая попытка отвечает 503, остальные - 200, поэтому повторяемое тело
+// отправляется дважды.
+func newBodyRoute(t *testing.T, buffering loadBalancer.BodyBufferingConfig) (*loadBalancer.LoadBalancerHandler, func() []receivedBody) {
+	var (
+		mu       sync.Mutex
+		received []receivedBody
+		attempts int64
+	)
+	record := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, err := io.ReadAll(r.Body)
+		assert.NoError(t, err)
+		mu.Lock()
+		received = append(received, receivedBody{body: body, contentLength: r.ContentLength})
+		mu.Unlock()
+		if atomic.AddInt64(&attempts, 1) == 1 {
+			w.WriteHeader(http.StatusServiceUnavailable)
+		}
+	})
+	route := newTestRoute(t, loadBalancer.RouteConfig{
+		Path:          "/upload",
+		BodyBuffering: buffering,
+		Retry:         loadBalancer.RetryPolicy{MaxAttempts: 2, RetryOnStatus: []int{http.StatusServiceUnavailable}, BackoffBase: time.Millisecond},
+	}, record, record)
+
+	return route.handler, func() []receivedBody {
+		mu.Lock()
+		defer mu.Unlock()
+		return append([]receivedBody(nil), received...)
+	}
+}
+
+// randomBody возвращает случайное тело заданного размера
+func randomBody(t *testing.T, size int) []byte {
+	body := make([]byte, size)
+	_, err := rand.Read(body)
+	require.NoError(t, err)
+	return body
+}
+
+func TestBodyPathsDeliverExactBytes(t *testing.T) {
+	cases := []struct {
+		name      string
+		buffering loadBalancer.BodyBufferingConfig
+		size      int
+		attempts  int
+		status    int
+	}{
+		// Тело в памяти и тело во временном файле отправляются повторно
+		{name: "memory", buffering: loadBalancer.BodyBufferingConfig{MaxMemory: 1 << 10}, size: 512, attempts: 2, status: http.StatusOK},
+		{name: "spooled", buffering: loadBalancer.BodyBufferingConfig{MaxMemory: 1 << 10, SpoolToDisk: true, SpoolDir: t.TempDir()}, size: 256 << 10, attempts: 2, status: http.StatusOK},
+		// Потоковое тело отправляется ровно один раз, ответ первой попытки уходит клиенту
+		{name: "streamed", buffering: loadBalancer.BodyBufferingConfig{MaxMemory: 1 << 10}, size: 256 << 10, attempts: 1, status: http.StatusServiceUnavailable},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			handler, received := newBodyRoute(t, tc.buffering)
+			payload := randomBody(t, tc.size)
+
+			rec := httptest.NewRecorder()
+			handler.ServeHTTP(rec, httptest.NewRequest(http.MethodPut, "/upload", bytes.NewReader(payload)))
+			assert.Equal(t, tc.status, rec.Code)
+
+			attempts := received()
+			require.Len(t, attempts, tc.attempts)
+			for i, attempt := range attempts {
+				assert.Equal(t, int64(tc.size), attempt.contentLength, "attempt %d: Content-Length", i+1)
+				assert.True(t, bytes.Equal(payload, attempt.body), "attempt %d: body differs", i+1)
+			}
+		})
+	}
+}
+
+func TestBodyWithUnknownLengthIsBuffered(t *testing.T) {
+	handler, received := newBodyRoute(t, loadBalancer.BodyBufferingConfig{MaxMemory: 1 << 10})
+	payload := randomBody(t, 700)
+
+	// Тело без Content-Length в пределах порога буферизуется: длина становится известна,
+	// и тело можно отправить повторно
+	req := httptest.NewRequest(http.MethodPut, "/upload", io.MultiReader(bytes.NewReader(payload)))
+	req.ContentLength = -1
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, req)
+	assert.Equal(t, http.StatusOK, rec.Code)
+
+	attempts := received()
+	require.Len(t, attempts, 2)
+	for _, attempt := range attempts {
+		assert.Equal(t, int64(len(payload)), attempt.contentLength)
+		assert.True(t, bytes.Equal(payload, attempt.body))
+	}
+}
@@ -0,0 +1,114 @@
+package integration
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+	"lb/internal/modules/certificates"
+	"math/big"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+// writeCert генерирует самоподписанный сертификат для dnsNames и сохраняет пару в dir
+func writeCert(t *testing.T, dir, name string, serial int64, dnsNames ...string) certificates.KeyPair {
+	t.Helper()
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+
+	template := &x509.Certificate{
+		SerialNumber: big.NewInt(serial),
+		Subject:      pkix.Name{CommonName: dnsNames[0]},
+		DNSNames:     dnsNames,
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	require.NoError(t, err)
+	keyDER, err := x509.MarshalECPrivateKey(key)
+	require.NoError(t, err)
+
+	pair := certificates.KeyPair{
+		CertFile: filepath.Join(dir, name+".pem"),
+		KeyFile:  filepath.Join(dir, name+"-key.pem"),
+	}
+	require.NoError(t, os.WriteFile(pair.CertFile, pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der}), 0o600))
+	require.NoError(t, os.WriteFile(pair.KeyFile, pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: keyDER}), 0o600))
+	return pair
+}
+
+func TestCertificateStoreSNIAndReload(t *testing.T) {
+	dir := t.TempDir()
+	api := writeCert(t, dir, "api", 1, "api.example.com")
+	wildcard := writeCert(t, dir, "wildcard", 2, "*.static.example.com")
+
+	store, err := certificates.NewStore([]certificates.KeyPair{api, wildcard}, zap.NewNop())
+	require.NoError(t, err)
+
+	serialFor := func(serverName string) int64 {
+		cert, err := store.GetCertificate(&tls.ClientHelloInfo{ServerName: serverName})
+		require.NoError(t, err)
+		return cert.Leaf.SerialNumber.Int64()
+	}
+	assert.Equal(t, int64(1), serialFor("api.example.com"))
+	assert.Equal(t, int64(2), serialFor("cdn.static.example.com"))
+	assert.Equal(t, int64(1), serialFor("unknown.example.org"), "first certificate is the default")
+
+	// Перевыпущенный сертификат подхватывается без пересоздания хранилища
+	writeCert(t, dir, "api", 3, "api.example.com")
+	require.NoError(t, store.Reload())
+	assert.Equal(t, int64(3), serialFor("api.example.com"))
+
+	_, err = certificates.ParseCipherSuites([]string{"TLS_FAKE"})
+	assert.Error(t, err)
+}
+
+func TestServerTLSRejectsInsecureSettings(t *testing.T) {
+	for _, version := range []string{"1.0", "1.1", "TLS1.1"} {
+		_, err := certificates.ParseVersion(version)
+		assert.Error(t, err, version)
+	}
+	version, err := certificates.ParseVersion("")
+	require.NoError(t, err)
+	assert.Equal(t, uint16(tls.VersionTLS12), version)
+	version, err = certificates.ParseVersion("1.3")
+	require.NoError(t, err)
+	assert.Equal(t, uint16(tls.VersionTLS13), version)
+
+	// Небезопасные наборы шифров отклоняются, даже если Go их знает
+	for _, suite := range tls.InsecureCipherSuites() {
+		_, err := certificates.ParseCipherSuites([]string{suite.Name})
+		assert.Error(t, err, suite.Name)
+	}
+	ids, err := certificates.ParseCipherSuites([]string{"TLS_ECDHE_RSA_WITH_AES_128_GCM_SHA256"})
+	require.NoError(t, err)
+	assert.Equal(t, []uint16{tls.TLS_ECDHE_RSA_WITH_AES_128_GCM_SHA256}, ids)
+}
+
+func TestCertificateWatchStopsWithContext(t *testing.T) {
+	store, err := certificates.NewStore([]certificates.KeyPair{writeCert(t, t.TempDir(), "api", 1, "api.example.com")}, zap.NewNop())
+	require.NoError(t, err)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	stopped := make(chan struct{})
+	go func() {
+		store.Watch(ctx, 10*time.Millisecond)
+		close(stopped)
+	}()
+	cancel()
+	select {
+	case <-stopped:
+	case <-time.After(time.Second):
+		t.Fatal("certificate watcher did not stop after context cancel")
+	}
+}
@@ -0,0 +1,172 @@
+package integration
+
+import (
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"io"
+	"lb/internal/modules/loadBalancer"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// newBreakerRoute поднимает маршрут из двух бэкендов: первый отвечает статусом из failingStatus,
+// второй всегда 200. Возвращает маршрут и счетчики обращений.
+func newBreakerRoute(t *testing.T, cfg loadBalancer.CircuitBreakerConfig, failingStatus *int64) (*testRoute, []*int64) {
+	hits := []*int64{new(int64), new(int64)}
+	handlers := make([]http.Handler, len(hits))
+	for i := range hits {
+		handlers[i] = http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			atomic.AddInt64(hits[i], 1)
+			if i == 0 {
+				w.WriteHeader(int(atomic.LoadInt64(failingStatus)))
+			}
+		})
+	}
+	return newTestRoute(t, loadBalancer.RouteConfig{Path: "/breaker", CircuitBreaker: cfg}, handlers...), hits
+}
+
+// breakerState возвращает состояние автомата бэкенда с заданным Id
+func breakerState(handler *loadBalancer.LoadBalancerHandler, id uint64) string {
+	for _, status := range handler.CircuitBreakers() {
+		if status.BackendId == id {
+			return status.State
+		}
+	}
+	return ""
+}
+
+func TestCircuitBreakerOpensAndRecovers(t *testing.T) {
+	status := int64(http.StatusInternalServerError)
+	route, hits := newBreakerRoute(t, loadBalancer.CircuitBreakerConfig{
+		Enabled:             true,
+		ConsecutiveFailures: 2,
+		OpenTimeout:         200 * time.Millisecond,
+	}, &status)
+	handler, failing := route.handler, route.backends[0].Id
+
+	for i := 0; i < 10; i++ {
+		handler.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/breaker", nil))
+	}
+	// После двух ошибок подряд бэкенд исключается из выбора
+	assert.Equal(t, int64(2), atomic.LoadInt64(hits[0]))
+	assert.Equal(t, int64(8), atomic.LoadInt64(hits[1]))
+	assert.Equal(t, loadBalancer.BreakerOpen, breakerState(handler, failing))
+
+	// Бэкенд восстановился: по истечении OpenTimeout пробный запрос замыкает автомат
+	atomic.StoreInt64(&status, http.StatusOK)
+	time.Sleep(250 * time.Millisecond)
+	for i := 0; i < 4; i++ {
+		handler.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/breaker", nil))
+	}
+	assert.Equal(t, loadBalancer.BreakerClosed, breakerState(handler, failing))
+	assert.Greater(t, atomic.LoadInt64(hits[0]), int64(2))
+}
+
+func TestCircuitBreakerReopensOnFailedProbe(t *testing.T) {
+	status := int64(http.StatusInternalServerError)
+	route, hits := newBreakerRoute(t, loadBalancer.CircuitBreakerConfig{
+		Enabled:     true,
+		ErrorRate:   0.5,
+		MinRequests: 2,
+		OpenTimeout: 100 * time.Millisecond,
+		// Порог ошибок подряд отключен, размыкание только по доле ошибок
+		ConsecutiveFailures: -1,
+	}, &status)
+	handler, failing := route.handler, route.backends[0].Id
+
+	for i := 0; i < 6; i++ {
+		handler.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/breaker", nil))
+	}
+	assert.Equal(t, loadBalancer.BreakerOpen, breakerState(handler, failing))
+	failedBefore := atomic.LoadInt64(hits[0])
+
+	// Пробный запрос снова неудачен - автомат размыкается повторно
+	time.Sleep(150 * time.Millisecond)
+	for i := 0; i < 4; i++ {
+		handler.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/breaker", nil))
+	}
+	assert.Equal(t, failedBefore+1, atomic.LoadInt64(hits[0]))
+	assert.Equal(t, loadBalancer.BreakerOpen, breakerState(handler, failing))
+}
+
+// slowBody отдает тело запроса с задержкой
+type slowBody struct{ data string }
+
+func (b slowBody) Read(p []byte) (int, error) {
+	time.Sleep(100 * time.Millisecond)
+	return copy(p, b.data), io.EOF
+}
+
+func TestCircuitBreakerHalfOpenAdmitsConcurrentProbesOnce(t *testing.T) {
+	// Первый бэкенд сначала падает, а после восстановления держит запросы до release
+	var failing atomic.Bool
+	failing.Store(true)
+	var probes int64
+	release := make(chan struct{})
+	first := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if failing.Load() {
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+		atomic.AddInt64(&probes, 1)
+		<-release
+	})
+	second := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {})
+	route := newTestRoute(t, loadBalancer.RouteConfig{
+		Path: "/breaker",
+		CircuitBreaker: loadBalancer.CircuitBreakerConfig{
+			Enabled:             true,
+			ConsecutiveFailures: 1,
+			OpenTimeout:         100 * time.Millisecond,
+			HalfOpenRequests:    1,
+		},
+	}, first, second)
+	handler, firstId := route.handler, route.backends[0].Id
+
+	for i := 0; i < 4 && breakerState(handler, firstId) != loadBalancer.BreakerOpen; i++ {
+		handler.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/breaker", nil))
+	}
+	require.Equal(t, loadBalancer.BreakerOpen, breakerState(handler, firstId))
+	failing.Store(false)
+	time.Sleep(150 * time.Millisecond)
+
+	// Параллельные запросы видят полуоткрытый автомат одновременно, но пробный слот один.
+	// Тело читается после выбора бэкенда и задерживает отправку, так что все запросы
+	// выбирают бэкенд раньше, чем первый из них до него дойдет.
+	const requests = 50
+	codes := make(chan int, requests)
+	start := make(chan struct{})
+	var wg sync.WaitGroup
+	for i := 0; i < requests; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			<-start
+			rec := httptest.NewRecorder()
+			handler.ServeHTTP(rec, httptest.NewRequest(http.MethodPost, "/breaker", slowBody{"payload"}))
+			codes <- rec.Code
+		}()
+	}
+	close(start)
+	require.Eventually(t, func() bool { return atomic.LoadInt64(&probes) == 1 }, time.Second, 5*time.Millisecond)
+	time.Sleep(100 * time.Millisecond)
+	assert.Equal(t, int64(1), atomic.LoadInt64(&probes))
+
+	close(release)
+	wg.Wait()
+	close(codes)
+	for code := range codes {
+		assert.Equal(t, http.StatusOK, code)
+	}
+	assert.Equal(t, loadBalancer.BreakerClosed, breakerState(handler, firstId))
+}
+
+func TestCircuitBreakerConfigValidation(t *testing.T) {
+	assert.NoError(t, loadBalancer.CircuitBreakerConfig{Enabled: true}.Validate())
+	assert.Error(t, loadBalancer.CircuitBreakerConfig{Enabled: true, ErrorRate: 1.5}.Validate())
+	assert.Error(t, loadBalancer.CircuitBreakerConfig{Enabled: true, Window: 100 * time.Millisecond}.Validate())
+}
@@ -0,0 +1,164 @@
+package integration
+
+import (
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	_ "lb/internal/app" // регистрирует проверки маршрутов для config.LoadConfig
+	"lb/internal/config"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// loadTestConfig сохраняет config.yaml с заданным содержимым во временный каталог
+// и загружает его через config.LoadConfig
+func loadTestConfig(t *testing.T, yaml string) (*config.Config, error) {
+	dir := t.TempDir()
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "config.yaml"), []byte(yaml), 0o600))
+	t.Chdir(dir)
+	return config.LoadConfig("config")
+}
+
+func TestLoadConfigAcceptsValidRoute(t *testing.T) {
+	cfg, err := loadTestConfig(t, `
+Routes:
+  - path: "/api"
+    algorithm: "consistent_hash"
+    hash_key: "header:X-User"
+    backends:
+      - url: "http://localhost:8081"
+        health: "/health"
+`)
+	require.NoError(t, err)
+	assert.Equal(t, "consistent_hash", cfg.Routes[0].Algorithm)
+}
+
+func TestLoadConfigRejectsInvalidRoutes(t *testing.T) {
+	// route дописывается к настройкам маршрута, backend - к настройкам его бэкенда
+	tests := []struct {
+		name    string
+		route   string
+		backend string
+		err     string
+	}{
+		{
+			name: "unknown algorithm",
+			route: `
+    algorithm: "fastest"`,
+			err: `invalid route "/api": unknown balancing algorithm "fastest"`,
+		},
+		{
+			name: "invalid algorithm options",
+			route: `
+    algorithm: "consistent_hash"
+    hash_key: "header"`,
+			err: `invalid route "/api": algorithm "consistent_hash": hash key "header" requires a name`,
+		},
+		{
+			name: "unknown backend protocol",
+			backend: `
+        protocol: "spdy"`,
+			err: `invalid route "/api": backend http://localhost:8081: unknown protocol "spdy"`,
+		},
+		{
+			name: "missing backend CA file",
+			backend: `
+        tls:
+          ca_file: "/nonexistent/ca.pem"`,
+			err: `invalid route "/api": backend http://localhost:8081: `,
+		},
+		{
+			name: "unknown host mode",
+			route: `
+    headers:
+      host: "sometimes"`,
+			err: `invalid route "/api": headers: unknown host mode "sometimes"`,
+		},
+		{
+			name: "negative retry attempts",
+			route: `
+    retry:
+      max_attempts: -1`,
+			err: `invalid route "/api": retry: max attempts must not be negative`,
+		},
+		{
+			name: "hedge without delay",
+			route: `
+    hedge:
+      enabled: true`,
+			err: `invalid route "/api": hedge delay must be positive`,
+		},
+		{
+			name: "circuit breaker error rate above one",
+			route: `
+    circuit_breaker:
+      enabled: true
+      error_rate: 1.5`,
+			err: `invalid route "/api": circuit breaker error rate must be in [0, 1]`,
+		},
+		{
+			name: "outlier latency factor below one",
+			route: `
+    outlier_detection:
+      enabled: true
+      latency_factor: 0.5`,
+			err: `invalid route "/api": outlier latency factor must be greater than 1`,
+		},
+		{
+			name: "unsupported health check type",
+			route: `
+    health_check:
+      type: "smtp"`,
+			err: `invalid route "/api": backend http://localhost:8081: unsupported health check type "smtp"`,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := loadTestConfig(t, `
+Routes:
+  - path: "/api"`+tt.route+`
+    backends:
+      - url: "http://localhost:8081"
+        health: "/health"`+tt.backend+`
+`)
+			require.Error(t, err)
+			assert.Contains(t, err.Error(), tt.err)
+		})
+	}
+}
+
+func TestLoadConfigRejectsInsecureTLS(t *testing.T) {
+	tests := []struct {
+		name string
+		tls  string
+		err  string
+	}{
+		{
+			name: "legacy version",
+			tls: `
+    min_version: "1.0"`,
+			err: `loadbalancer tls: TLS version "1.0" is insecure`,
+		},
+		{
+			name: "insecure cipher",
+			tls: `
+    cipher_suites: ["TLS_RSA_WITH_RC4_128_SHA"]`,
+			err: `loadbalancer tls: cipher suite "TLS_RSA_WITH_RC4_128_SHA" is insecure`,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := loadTestConfig(t, `
+LoadBalancer:
+  address: ":8443"
+  tls:
+    enabled: true
+    certificates:
+      - cert_file: "cert.pem"
+        key_file: "key.pem"`+tt.tls+`
+`)
+			require.Error(t, err)
+			assert.Contains(t, err.Error(), tt.err)
+		})
+	}
+}
@@ -0,0 +1,99 @@
+package integration
+
+import (
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"lb/internal/modules/backends"
+	"lb/internal/modules/backends/models"
+	"lb/internal/modules/loadBalancer"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+// newHeadersFront поднимает балансировщик с одним HTTP/1.1-бэкендом, который сохраняет полученный запрос.
+// HTTP/1.1 нужен, чтобы Connection и другие hop-by-hop заголовки вообще доходили по сети.
+func newHeadersFront(t *testing.T, cfg loadBalancer.ProxyHeadersConfig) (string, <-chan *http.Request) {
+	received := make(chan *http.Request, 1)
+	handler := newTestRoute(t, loadBalancer.RouteConfig{
+		Path:     "/",
+		Headers:  cfg,
+		Backends: []models.Backend{{Protocol: backends.ProtocolHTTP1}},
+	}, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		received <- r
+		w.Header().Set("Connection", "X-Internal")
+		w.Header().Set("X-Internal", "secret")
+		w.Header().Set("Keep-Alive", "timeout=5")
+		w.Header().Set("X-App", "ok")
+	})).handler
+
+	front := httptest.NewServer(handler)
+	t.Cleanup(front.Close)
+	return front.URL, received
+}
+
+func TestForwardedHeadersFromUntrustedClient(t *testing.T) {
+	frontURL, received := newHeadersFront(t, loadBalancer.ProxyHeadersConfig{Forwarded: true})
+
+	req, err := http.NewRequest(http.MethodGet, frontURL+"/", nil)
+	require.NoError(t, err)
+	req.Host = "app.example.com"
+	req.Header.Set("X-Forwarded-For", "6.6.6.6")
+	req.Header.Set("X-Forwarded-Proto", "https")
+	req.Header.Set("Forwarded", "for=6.6.6.6")
+	req.Header.Set("Connection", "X-Secret")
+	req.Header.Set("X-Secret", "token")
+	req.Header.Set("Proxy-Authorization", "Basic Zm9vOmJhcg==")
+
+	resp, err := http.DefaultClient.Do(req)
+	require.NoError(t, err)
+	resp.Body.Close()
+
+	got := <-received
+	// Подделанные клиентом значения заменяются адресом непосредственного клиента
+	assert.Equal(t, "127.0.0.1", got.Header.Get("X-Forwarded-For"))
+	assert.Equal(t, "http", got.Header.Get("X-Forwarded-Proto"))
+	assert.Equal(t, "app.example.com", got.Header.Get("X-Forwarded-Host"))
+	assert.Equal(t, "for=127.0.0.1;host=app.example.com;proto=http", got.Header.Get("Forwarded"))
+	// Hop-by-hop заголовки, включая перечисленные в Connection, не доходят до бэкенда
+	assert.Empty(t, got.Header.Get("X-Secret"))
+	assert.Empty(t, got.Header.Get("Proxy-Authorization"))
+	// По умолчанию Host переписывается на адрес бэкенда
+	assert.NotEqual(t, "app.example.com", got.Host)
+
+	// И не возвращаются клиенту в ответе
+	assert.Empty(t, resp.Header.Get("X-Internal"))
+	assert.Empty(t, resp.Header.Get("Keep-Alive"))
+	assert.Equal(t, "ok", resp.Header.Get("X-App"))
+}
+
+func TestForwardedHeadersFromTrustedProxy(t *testing.T) {
+	frontURL, received := newHeadersFront(t, loadBalancer.ProxyHeadersConfig{
+		Host:           loadBalancer.HostPreserve,
+		TrustedProxies: []string{"127.0.0.0/8"},
+		Forwarded:      true,
+	})
+
+	req, err := http.NewRequest(http.MethodGet, frontURL+"/", nil)
+	require.NoError(t, err)
+	req.Host = "app.example.com"
+	req.Header.Set("X-Forwarded-For", "203.0.113.7")
+	req.Header.Set("X-Forwarded-Proto", "https")
+	req.Header.Set("Forwarded", "for=203.0.113.7;proto=https")
+
+	resp, err := http.DefaultClient.Do(req)
+	require.NoError(t, err)
+	resp.Body.Close()
+
+	got := <-received
+	assert.Equal(t, "203.0.113.7, 127.0.0.1", got.Header.Get("X-Forwarded-For"))
+	assert.Equal(t, "https", got.Header.Get("X-Forwarded-Proto"))
+	assert.Equal(t, "for=203.0.113.7;proto=https, for=127.0.0.1;host=app.example.com;proto=http", got.Header.Get("Forwarded"))
+	assert.Equal(t, "app.example.com", got.Host)
+}
+
+func TestProxyHeadersConfigValidation(t *testing.T) {
+	assert.NoError(t, loadBalancer.ProxyHeadersConfig{TrustedProxies: []string{"10.0.0.0/8", "::1"}}.Validate())
+	assert.Error(t, loadBalancer.ProxyHeadersConfig{TrustedProxies: []string{"10.0.0.0/33"}}.Validate())
+	assert.Error(t, loadBalancer.ProxyHeadersConfig{Host: "upstream"}.Validate())
+}
@@ -0,0 +1,212 @@
+package integration
+
+import (
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+	"lb/internal/modules/backends"
+	"lb/internal/modules/backends/models"
+	"lb/internal/modules/healthchecker"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// startHealthCheck запускает проверку одного бэкенда и возвращает канал его статусов
+func startHealthCheck(t *testing.T, backend models.Backend) <-chan models.BackendStatus {
+	registry := backends.NewBackendRegistry()
+	client := &http.Client{Timeout: 5 * time.Second}
+	hc := healthchecker.NewHealthChecker(20*time.Millisecond, 20*time.Millisecond, registry, client, zap.NewNop())
+	updates := registry.Subscribe(backend.Id)
+	hc.Start()
+	require.NoError(t, hc.AddBackend(&backend))
+	return updates
+}
+
+// waitHealthy ждет статуса здоровья бэкенда; false - за отведенное время статус не пришел
+func waitHealthy(updates <-chan models.BackendStatus, timeout time.Duration) bool {
+	deadline := time.After(timeout)
+	for {
+		select {
+		case status := <-updates:
+			if status.IsHealthy {
+				return true
+			}
+		case <-deadline:
+			return false
+		}
+	}
+}
+
+func TestHealthCheckCustomCriteria(t *testing.T) {
+	backend := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Host != "api.internal" || r.Header.Get("X-Health-Check") != "lb" {
+			w.WriteHeader(http.StatusForbidden)
+			return
+		}
+		w.WriteHeader(http.StatusAccepted)
+		w.Write([]byte(`{"status": "up"}`))
+	}))
+	defer backend.Close()
+
+	updates := startHealthCheck(t, models.Backend{
+		Id:     9901,
+		URL:    backend.URL,
+		Health: "/health",
+		Check: models.HealthCheck{
+			ExpectedStatus: []string{"200-204"},
+			BodyRegex:      `"status":\s*"up"`,
+			Headers:        map[string]string{"X-Health-Check": "lb"},
+			Host:           "api.internal",
+		},
+	})
+	assert.True(t, waitHealthy(updates, time.Second))
+}
+
+func TestHealthCheckBodyMismatchIsUnhealthy(t *testing.T) {
+	var checks int64
+	backend := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt64(&checks, 1)
+		w.Write([]byte(`{"status": "degraded"}`))
+	}))
+	defer backend.Close()
+
+	updates := startHealthCheck(t, models.Backend{
+		Id: 9902, URL: backend.URL, Health: "/health",
+		Check: models.HealthCheck{BodyContains: `"up"`},
+	})
+	assert.False(t, waitHealthy(updates, 300*time.Millisecond))
+	assert.Greater(t, atomic.LoadInt64(&checks), int64(1))
+}
+
+func TestHealthCheckTimeoutOverridesClient(t *testing.T) {
+	backend := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		time.Sleep(300 * time.Millisecond)
+	}))
+	defer backend.Close()
+
+	// Общий клиент ждал бы 5 секунд, проверка ограничена 50ms
+	updates := startHealthCheck(t, models.Backend{
+		Id: 9903, URL: backend.URL, Health: "/health",
+		Check: models.HealthCheck{Timeout: 50 * time.Millisecond},
+	})
+	assert.False(t, waitHealthy(updates, 500*time.Millisecond))
+}
+
+func TestHealthCheckReusesConnections(t *testing.T) {
+	var checks, conns int64
+	backend := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt64(&checks, 1)
+		w.Write([]byte("ok"))
+	}))
+	backend.Config.ConnState = func(c net.Conn, state http.ConnState) {
+		if state == http.StateNew {
+			atomic.AddInt64(&conns, 1)
+		}
+	}
+	backend.Start()
+	defer backend.Close()
+
+	updates := startHealthCheck(t, models.Backend{
+		Id: 9904, URL: backend.URL, Health: "/health",
+		Check: models.HealthCheck{Method: http.MethodGet, ExpectedStatus: []string{"200"}},
+	})
+	require.True(t, waitHealthy(updates, time.Second))
+	time.Sleep(200 * time.Millisecond)
+
+	// Тела ответов закрываются, поэтому все проверки идут по одному соединению
+	assert.Greater(t, atomic.LoadInt64(&checks), int64(3))
+	assert.Equal(t, int64(1), atomic.LoadInt64(&conns))
+}
+
+func TestHealthCheckValidation(t *testing.T) {
+	assert.NoError(t, healthchecker.ValidateHealthCheck(models.HealthCheck{ExpectedStatus: []string{"200", "300-399"}}))
+	assert.Error(t, healthchecker.ValidateHealthCheck(models.HealthCheck{ExpectedStatus: []string{"299-200"}}))
+	assert.Error(t, healthchecker.ValidateHealthCheck(models.HealthCheck{Method: "POST"}))
+	assert.Error(t, healthchecker.ValidateHealthCheck(models.HealthCheck{Method: "HEAD", BodyContains: "ok"}))
+	assert.Error(t, healthchecker.ValidateHealthCheck(models.HealthCheck{BodyRegex: "("}))
+	assert.Error(t, healthchecker.ValidateHealthCheck(models.HealthCheck{Rise: -1}))
+}
+
+func TestHealthCheckRiseRequiresConsecutivePasses(t *testing.T) {
+	var checks, recovered int64
+	backend := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt64(&checks, 1)
+		if atomic.LoadInt64(&recovered) == 0 {
+			w.WriteHeader(http.StatusServiceUnavailable)
+		}
+	}))
+	defer backend.Close()
+
+	// Интервал для здоровых заведомо длиннее теста: пока набирается rise,
+	// проверки идут с интервалом для нездоровых
+	registry := backends.NewBackendRegistry()
+	hc := healthchecker.NewHealthChecker(time.Minute, 20*time.Millisecond, registry, &http.Client{Timeout: 5 * time.Second}, zap.NewNop())
+	updates := registry.Subscribe(9905)
+	hc.Start()
+	require.NoError(t, hc.AddBackend(&models.Backend{
+		Id: 9905, URL: backend.URL, Health: "/health",
+		Check: models.HealthCheck{Rise: 3},
+	}))
+	require.Eventually(t, func() bool { return atomic.LoadInt64(&checks) > 0 }, time.Second, 5*time.Millisecond)
+
+	atomic.StoreInt64(&recovered, 1)
+	before := atomic.LoadInt64(&checks)
+	require.True(t, waitHealthy(updates, time.Second))
+	assert.GreaterOrEqual(t, atomic.LoadInt64(&checks)-before, int64(3))
+}
+
+func TestHealthCheckFirstPassSkipsRise(t *testing.T) {
+	var checks int64
+	backend := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt64(&checks, 1)
+	}))
+	defer backend.Close()
+
+	// При старте бэкенд вводится в работу первой успешной проверкой, не дожидаясь rise
+	registry := backends.NewBackendRegistry()
+	hc := healthchecker.NewHealthChecker(time.Minute, time.Minute, registry, &http.Client{Timeout: 5 * time.Second}, zap.NewNop())
+	updates := registry.Subscribe(9907)
+	hc.Start()
+	require.NoError(t, hc.AddBackend(&models.Backend{
+		Id: 9907, URL: backend.URL, Health: "/health",
+		Check: models.HealthCheck{Rise: 3},
+	}))
+	require.True(t, waitHealthy(updates, time.Second))
+	assert.Equal(t, int64(1), atomic.LoadInt64(&checks))
+}
+
+func TestHealthCheckFallIgnoresSingleFailures(t *testing.T) {
+	var checks, failing int64
+	backend := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		n := atomic.AddInt64(&checks, 1)
+		// Сначала каждая вторая проверка неудачна, затем бэкенд падает совсем
+		if atomic.LoadInt64(&failing) == 1 || n%2 == 0 {
+			w.WriteHeader(http.StatusServiceUnavailable)
+		}
+	}))
+	defer backend.Close()
+
+	updates := startHealthCheck(t, models.Backend{
+		Id: 9906, URL: backend.URL, Health: "/health",
+		Check: models.HealthCheck{Fall: 2},
+	})
+	require.True(t, waitHealthy(updates, time.Second))
+
+	select {
+	case status := <-updates:
+		t.Fatalf("unexpected status change on single failures: %+v", status)
+	case <-time.After(300 * time.Millisecond):
+	}
+
+	atomic.StoreInt64(&failing, 1)
+	select {
+	case status := <-updates:
+		assert.False(t, status.IsHealthy)
+	case <-time.After(time.Second):
+		t.Fatal("backend was not marked unhealthy")
+	}
+}
@@ -0,0 +1,116 @@
+package integration
+
+import (
+	"bytes"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"golang.org/x/net/http2"
+	"golang.org/x/net/http2/h2c"
+	"io"
+	"lb/internal/modules/backends/models"
+	"lb/internal/modules/healthchecker"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+// newGRPCHealthServer запускает h2c-сервер grpc.health.v1.Health, отвечающий status для сервиса service
+func newGRPCHealthServer(service string, status byte) *httptest.Server {
+	return httptest.NewServer(h2c.NewHandler(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		w.Header().Set("Content-Type", "application/grpc")
+		w.Header().Set("Trailer", "Grpc-Status")
+		if r.URL.Path != "/grpc.health.v1.Health/Check" || !bytes.Contains(body, []byte(service)) {
+			w.Header().Set("Grpc-Status", "5") // NOT_FOUND
+			return
+		}
+		w.Write([]byte{0, 0, 0, 0, 2, 0x08, status})
+		w.Header().Set("Grpc-Status", "0")
+	}), &http2.Server{}))
+}
+
+func TestHealthCheckTCP(t *testing.T) {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	defer listener.Close()
+	go func() {
+		for {
+			conn, err := listener.Accept()
+			if err != nil {
+				return
+			}
+			conn.Close()
+		}
+	}()
+
+	updates := startHealthCheck(t, models.Backend{
+		Id: 9911, URL: "http://" + listener.Addr().String(),
+		Check: models.HealthCheck{Type: healthchecker.CheckTCP},
+	})
+	assert.True(t, waitHealthy(updates, time.Second))
+
+	// Порт закрытого листенера соединения не принимает
+	closed, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	addr := closed.Addr().String()
+	closed.Close()
+	updates = startHealthCheck(t, models.Backend{
+		Id: 9912, URL: "http://" + addr,
+		Check: models.HealthCheck{Type: healthchecker.CheckTCP},
+	})
+	assert.False(t, waitHealthy(updates, 200*time.Millisecond))
+}
+
+func TestHealthCheckGRPC(t *testing.T) {
+	serving := newGRPCHealthServer("payments", 1)
+	defer serving.Close()
+	updates := startHealthCheck(t, models.Backend{
+		Id: 9913, URL: serving.URL,
+		Check: models.HealthCheck{Type: healthchecker.CheckGRPC, Service: "payments"},
+	})
+	assert.True(t, waitHealthy(updates, time.Second))
+
+	notServing := newGRPCHealthServer("payments", 2)
+	defer notServing.Close()
+	updates = startHealthCheck(t, models.Backend{
+		Id: 9914, URL: notServing.URL,
+		Check: models.HealthCheck{Type: healthchecker.CheckGRPC, Service: "payments"},
+	})
+	assert.False(t, waitHealthy(updates, 200*time.Millisecond))
+
+	// Неизвестный сервис: вызов завершается со статусом NOT_FOUND
+	updates = startHealthCheck(t, models.Backend{
+		Id: 9915, URL: serving.URL,
+		Check: models.HealthCheck{Type: healthchecker.CheckGRPC, Service: "orders"},
+	})
+	assert.False(t, waitHealthy(updates, 200*time.Millisecond))
+}
+
+func TestHealthCheckExec(t *testing.T) {
+	updates := startHealthCheck(t, models.Backend{
+		Id: 9916, URL: "http://db.internal:5432",
+		Check: models.HealthCheck{
+			Type:    healthchecker.CheckExec,
+			Command: []string{"sh", "-c", `test "$LB_BACKEND_URL" = "http://db.internal:5432"`},
+		},
+	})
+	assert.True(t, waitHealthy(updates, time.Second))
+
+	updates = startHealthCheck(t, models.Backend{
+		Id: 9917, URL: "http://db.internal:5432",
+		Check: models.HealthCheck{Type: healthchecker.CheckExec, Command: []string{"sh", "-c", "exit 1"}},
+	})
+	assert.False(t, waitHealthy(updates, 200*time.Millisecond))
+}
+
+func TestHealthCheckTypeValidation(t *testing.T) {
+	assert.NoError(t, healthchecker.ValidateHealthCheck(models.HealthCheck{Type: "TCP"}))
+	assert.NoError(t, healthchecker.ValidateHealthCheck(models.HealthCheck{Type: "grpc", Service: "payments"}))
+	assert.Error(t, healthchecker.ValidateHealthCheck(models.HealthCheck{Type: "udp"}))
+	assert.Error(t, healthchecker.ValidateHealthCheck(models.HealthCheck{Type: "exec"}))
+	assert.Error(t, healthchecker.ValidateHealthCheck(models.HealthCheck{Type: "tcp", ExpectedStatus: []string{"200"}}))
+	assert.Error(t, healthchecker.ValidateHealthCheck(models.HealthCheck{Type: "http", Command: []string{"true"}}))
+	assert.Error(t, healthchecker.ValidateHealthCheck(models.HealthCheck{Service: "payments"}))
+}
@@ -0,0 +1,198 @@
+package integration
+
+import (
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"io"
+	"lb/internal/modules/loadBalancer"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// newHedgeRoute поднимает маршрут с бэкендами, отвечающими с заданными задержками
+func newHedgeRoute(t *testing.T, hedge loadBalancer.HedgeConfig, delays ...time.Duration) *loadBalancer.LoadBalancerHandler {
+	handlers := make([]http.Handler, len(delays))
+	for i, delay := range delays {
+		handlers[i] = http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			select {
+			case <-time.After(delay):
+			case <-r.Context().Done():
+				return
+			}
+			w.Write([]byte(delay.String()))
+		})
+	}
+	return newTestRoute(t, loadBalancer.RouteConfig{Path: "/hedge", Hedge: hedge}, handlers...).handler
+}
+
+func TestHedgedRequestFirstResponseWins(t *testing.T) {
+	handler := newHedgeRoute(t, loadBalancer.HedgeConfig{Enabled: true, Delay: 20 * time.Millisecond},
+		2*time.Second, 0)
+
+	// Round robin по очереди выбирает медленный бэкенд первым; хедж отвечает быстрее
+	for i := 0; i < 4; i++ {
+		start := time.Now()
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/hedge", nil))
+		assert.Equal(t, http.StatusOK, rec.Code)
+		assert.Equal(t, "0s", rec.Body.String())
+		assert.Less(t, time.Since(start), time.Second)
+	}
+
+	stats := handler.HedgeStats()
+	require.NotNil(t, stats)
+	assert.Positive(t, stats.Hedged)
+	assert.Equal(t, stats.Hedged, stats.Wins)
+}
+
+func TestHedgingSkipsNonIdempotentRequests(t *testing.T) {
+	handler := newHedgeRoute(t, loadBalancer.HedgeConfig{Enabled: true, Delay: 10 * time.Millisecond},
+		100*time.Millisecond, 100*time.Millisecond)
+
+	handler.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodPost, "/hedge", nil))
+	assert.Zero(t, handler.HedgeStats().Hedged)
+}
+
+func TestHedgingRespectsInFlightLimit(t *testing.T) {
+	handler := newHedgeRoute(t, loadBalancer.HedgeConfig{Enabled: true, Delay: 10 * time.Millisecond, MaxInFlight: 1},
+		300*time.Millisecond, 300*time.Millisecond)
+
+	var wg sync.WaitGroup
+	for i := 0; i < 4; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			handler.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/hedge", nil))
+		}()
+	}
+	wg.Wait()
+
+	stats := handler.HedgeStats()
+	assert.Equal(t, uint64(1), stats.Hedged)
+	assert.Equal(t, uint64(3), stats.Rejected)
+}
+
+func TestHedgeSlotHeldUntilStreamingBodyCloses(t *testing.T) {
+	// Медленный бэкенд отвечает через 300ms, потоковый сразу отдает заголовки,
+	// а тело держит открытым до release
+	release := make(chan struct{})
+	slow := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		select {
+		case <-time.After(300 * time.Millisecond):
+		case <-r.Context().Done():
+			return
+		}
+		w.Write([]byte("slow"))
+	})
+	stream := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("chunk"))
+		w.(http.Flusher).Flush()
+		select {
+		case <-release:
+		case <-r.Context().Done():
+		}
+	})
+	handler := newTestRoute(t, loadBalancer.RouteConfig{
+		Path:  "/hedge",
+		Hedge: loadBalancer.HedgeConfig{Enabled: true, Delay: 10 * time.Millisecond, MaxInFlight: 1},
+	}, slow, stream).handler
+
+	var wg sync.WaitGroup
+	send := func() {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			handler.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/hedge", nil))
+		}()
+		time.Sleep(100 * time.Millisecond)
+	}
+
+	// sendUntil отправляет запросы, пока не выполнится условие (не больше четырех)
+	sendUntil := func(condition func(loadBalancer.HedgeStats) bool) {
+		for i := 0; i < 4 && !condition(*handler.HedgeStats()); i++ {
+			send()
+		}
+		require.True(t, condition(*handler.HedgeStats()))
+	}
+
+	// Запрос, начавшийся с медленного бэкенда, хеджируется на потоковый,
+	// который выигрывает и держит тело открытым
+	sendUntil(func(stats loadBalancer.HedgeStats) bool { return stats.Hedged == 1 })
+
+	// Пока поток хеджирующего ответа открыт, место занято и новый хедж отклоняется
+	sendUntil(func(stats loadBalancer.HedgeStats) bool { return stats.Rejected == 1 })
+	assert.Equal(t, uint64(1), handler.HedgeStats().Hedged)
+
+	// Тело закрыто - место освобождено
+	close(release)
+	wg.Wait()
+	sendUntil(func(stats loadBalancer.HedgeStats) bool { return stats.Hedged == 2 })
+	assert.Equal(t, uint64(1), handler.HedgeStats().Rejected)
+	wg.Wait()
+}
+
+func TestHedgedStreamOutlivesBackendTimeout(t *testing.T) {
+	t.Parallel()
+	// Поток длиннее общего дедлайна попытки (10s), но паузы между событиями короткие
+	const events = 12
+	handler := newTestRoute(t, loadBalancer.RouteConfig{
+		Path:  "/hedge",
+		Hedge: loadBalancer.HedgeConfig{Enabled: true, Delay: 10 * time.Millisecond},
+	}, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/event-stream")
+		for i := 0; i < events; i++ {
+			w.Write([]byte("data: tick\n\n"))
+			w.(http.Flusher).Flush()
+			time.Sleep(time.Second)
+		}
+	})).handler
+	front := httptest.NewServer(handler)
+	defer front.Close()
+
+	resp, err := http.Get(front.URL + "/hedge/events")
+	require.NoError(t, err)
+	body, err := io.ReadAll(resp.Body)
+	resp.Body.Close()
+	require.NoError(t, err)
+	assert.Equal(t, strings.Repeat("data: tick\n\n", events), string(body))
+}
+
+func TestHedgedStreamEndsOnIdleTimeout(t *testing.T) {
+	release := make(chan struct{})
+	defer close(release)
+	handler := newTestRoute(t, loadBalancer.RouteConfig{
+		Path:      "/hedge",
+		Hedge:     loadBalancer.HedgeConfig{Enabled: true, Delay: 10 * time.Millisecond},
+		Streaming: loadBalancer.StreamingConfig{IdleTimeout: 200 * time.Millisecond},
+	}, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/event-stream")
+		w.Write([]byte("data: first\n\n"))
+		w.(http.Flusher).Flush()
+		select {
+		case <-release:
+		case <-r.Context().Done():
+		}
+	})).handler
+	front := httptest.NewServer(handler)
+	defer front.Close()
+
+	// Ответ хеджируемого маршрута тоже переходит на таймаут простоя
+	start := time.Now()
+	resp, err := http.Get(front.URL + "/hedge/events")
+	require.NoError(t, err)
+	body, _ := io.ReadAll(resp.Body)
+	resp.Body.Close()
+	assert.Equal(t, "data: first\n\n", string(body))
+	assert.Less(t, time.Since(start), time.Second)
+}
+
+func TestHedgeConfigValidation(t *testing.T) {
+	assert.NoError(t, loadBalancer.HedgeConfig{}.Validate())
+	assert.NoError(t, loadBalancer.HedgeConfig{Enabled: true, Delay: time.Millisecond, Percentile: 95}.Validate())
+	assert.Error(t, loadBalancer.HedgeConfig{Enabled: true}.Validate())
+	assert.Error(t, loadBalancer.HedgeConfig{Enabled: true, Delay: time.Millisecond, Percentile: 100}.Validate())
+}
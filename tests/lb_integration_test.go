@@ -97,14 +97,15 @@ func TestLoadBalancerIntegration(t *testing.T) {
 	}
 
 	// 5. Создаем балансировщики и rate limiter
-	lbMap := loadBalancer.CreateLoadBalancers(routers, registry, hc, logger)
+	lbMap, err := loadBalancer.CreateLoadBalancers(routers, registry, hc, logger)
+	require.NoError(t, err)
 	rateLimiter := rateLimiter.NewTokenBucketLimiter(ctx, testConfig.RateLimiter.Limit, 30*time.Second, logger)
 
 	// 6. Запускаем health checker
 	go hc.Start()
 
 	// 7. Создаем тестовый сервер
-	routes := routes.CreateRouter(lbMap, rateLimiter, logger)
+	routes := routes.CreateRouter(ctx, lbMap, rateLimiter, registry, hc, "", logger)
 	testServer := httptest.NewServer(routes)
 	defer testServer.Close()
 
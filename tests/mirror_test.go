@@ -0,0 +1,166 @@
+package integration
+
+import (
+	"context"
+	"encoding/json"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+	routes "lb/internal/modules"
+	"lb/internal/modules/backends/models"
+	"lb/internal/modules/healthchecker"
+	"lb/internal/modules/loadBalancer"
+	"lb/internal/modules/rateLimiter"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// newMirrorRoute поднимает маршрут с одним бэкендом, отвечающим "primary",
+// и зеркалом mirror
+func newMirrorRoute(t *testing.T, mirror loadBalancer.MirrorConfig) *testRoute {
+	return newTestRoute(t, loadBalancer.RouteConfig{Path: "/mirror", Mirror: mirror},
+		http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.Write([]byte("primary"))
+		}))
+}
+
+func TestMirrorSamplesRequests(t *testing.T) {
+	var mirrored int64
+	mirror := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt64(&mirrored, 1)
+	}))
+	defer mirror.Close()
+	handler := newMirrorRoute(t, loadBalancer.MirrorConfig{URL: mirror.URL, Percent: 25, QueueSize: 1000}).handler
+
+	const requests = 400
+	for i := 0; i < requests; i++ {
+		handler.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/mirror", nil))
+	}
+
+	stats := handler.MirrorStats()
+	require.NotNil(t, stats)
+	assert.InDelta(t, requests/4, stats.Queued, 40)
+	assert.Zero(t, stats.Dropped)
+	require.Eventually(t, func() bool {
+		return atomic.LoadInt64(&mirrored) == int64(stats.Queued)
+	}, 2*time.Second, 10*time.Millisecond)
+}
+
+func TestMirrorReceivesClientRequest(t *testing.T) {
+	received := make(chan *http.Request, 1)
+	mirror := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		received <- r
+	}))
+	defer mirror.Close()
+
+	// Основной бэкенд с базовым путем получает заголовки X-Forwarded-*
+	handler := newTestRoute(t, loadBalancer.RouteConfig{
+		Path:     "/mirror",
+		Mirror:   loadBalancer.MirrorConfig{URL: mirror.URL, Percent: 100},
+		Headers:  loadBalancer.ProxyHeadersConfig{Forwarded: true},
+		Backends: []models.Backend{{URL: "/v1"}},
+	}, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {})).handler
+
+	req := httptest.NewRequest(http.MethodGet, "/mirror/items?page=2", nil)
+	req.Host = "app.example.com"
+	req.Header.Set("X-Request-Id", "42")
+	req.Header.Set("Connection", "X-Secret")
+	req.Header.Set("X-Secret", "token")
+	handler.ServeHTTP(httptest.NewRecorder(), req)
+
+	// Зеркало видит запрос клиента, а не запрос к основному бэкенду
+	select {
+	case got := <-received:
+		assert.Equal(t, "/mirror/items", got.URL.Path)
+		assert.Equal(t, "page=2", got.URL.RawQuery)
+		assert.Equal(t, "42", got.Header.Get("X-Request-Id"))
+		assert.Empty(t, got.Header.Get("X-Forwarded-For"))
+		assert.Empty(t, got.Header.Get("Forwarded"))
+		assert.Empty(t, got.Header.Get("X-Secret"))
+	case <-time.After(time.Second):
+		t.Fatal("request copy did not reach the mirror")
+	}
+}
+
+func TestMirrorDoesNotBlockAndCountsDrops(t *testing.T) {
+	release := make(chan struct{})
+	var received int64
+	mirror := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt64(&received, 1)
+		<-release
+	}))
+	defer mirror.Close()
+	defer close(release)
+
+	route := newMirrorRoute(t, loadBalancer.MirrorConfig{URL: mirror.URL, Percent: 100, QueueSize: 1})
+	handler := route.handler
+
+	// Зеркало зависло: воркеры заняты, очередь заполнена, но основные запросы не ждут
+	const requests = 50
+	start := time.Now()
+	for i := 0; i < requests; i++ {
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, httptest.NewRequest(http.MethodPost, "/mirror", strings.NewReader("payload")))
+		assert.Equal(t, "primary", rec.Body.String())
+	}
+	assert.Less(t, time.Since(start), time.Second)
+
+	stats := handler.MirrorStats()
+	assert.Positive(t, stats.Dropped)
+	assert.Equal(t, uint64(requests), stats.Queued+stats.Dropped)
+
+	// Потерянные копии видны в admin API
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	logger := zap.NewNop()
+	hc := healthchecker.NewHealthChecker(time.Minute, time.Minute, route.registry, http.DefaultClient, logger)
+	router := routes.CreateRouter(ctx, route.lbMap, rateLimiter.NewTokenBucketLimiter(ctx, 100, 30*time.Second, logger), route.registry, hc, "", logger)
+	rec := httptest.NewRecorder()
+	router.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/admin/mirrors", nil))
+	require.Equal(t, http.StatusOK, rec.Code)
+	var result []struct {
+		Path    string `json:"path"`
+		Dropped uint64 `json:"dropped"`
+	}
+	require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &result))
+	require.Len(t, result, 1)
+	assert.Equal(t, "/mirror", result[0].Path)
+	assert.GreaterOrEqual(t, result[0].Dropped, stats.Dropped)
+}
+
+func TestMirrorResponseIsDiscarded(t *testing.T) {
+	mirrored := make(chan string, 1)
+	mirror := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body := make([]byte, 64)
+		n, _ := r.Body.Read(body)
+		mirrored <- r.Method + " " + r.URL.Path + " " + string(body[:n])
+		w.Header().Set("X-Mirror", "1")
+		http.SetCookie(w, &http.Cookie{Name: "mirror", Value: "1"})
+		w.WriteHeader(http.StatusInternalServerError)
+		w.Write([]byte("mirror"))
+	}))
+	defer mirror.Close()
+	handler := newMirrorRoute(t, loadBalancer.MirrorConfig{URL: mirror.URL, Percent: 100}).handler
+
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, httptest.NewRequest(http.MethodPost, "/mirror", strings.NewReader("payload")))
+
+	// Зеркало получило копию запроса, а клиент - только ответ основного бэкенда
+	select {
+	case got := <-mirrored:
+		assert.Equal(t, "POST /mirror payload", got)
+	case <-time.After(time.Second):
+		t.Fatal("mirror did not receive the request copy")
+	}
+	assert.Equal(t, http.StatusOK, rec.Code)
+	assert.Equal(t, "primary", rec.Body.String())
+	assert.Empty(t, rec.Header().Get("X-Mirror"))
+	assert.Empty(t, rec.Result().Cookies())
+	require.Eventually(t, func() bool {
+		return handler.MirrorStats().Failed == 0 && handler.MirrorStats().Queued == 1
+	}, time.Second, 10*time.Millisecond)
+}
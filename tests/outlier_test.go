@@ -0,0 +1,119 @@
+package integration
+
+import (
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"lb/internal/modules/backends/models"
+	"lb/internal/modules/loadBalancer"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestOutlierDetectionEjectsThroughRegistry(t *testing.T) {
+	var failingHits int64
+	handlers := make([]http.Handler, 3)
+	for i := range handlers {
+		handlers[i] = http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if i == 0 {
+				atomic.AddInt64(&failingHits, 1)
+				w.WriteHeader(http.StatusServiceUnavailable)
+			}
+		})
+	}
+	route := newTestRoute(t, loadBalancer.RouteConfig{
+		Path:  "/outlier",
+		Retry: loadBalancer.RetryPolicy{MaxAttempts: 1},
+		OutlierDetection: loadBalancer.OutlierDetectionConfig{
+			Enabled:                  true,
+			ConsecutiveGatewayErrors: 2,
+			BaseEjectionTime:         300 * time.Millisecond,
+		},
+	}, handlers...)
+	handler, failing := route.handler, route.backends[0].Id
+	// Сторонний подписчик видит исключение так же, как балансировщик
+	updates := route.registry.Subscribe(failing)
+
+	for i := 0; i < 12; i++ {
+		handler.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/outlier", nil))
+	}
+	assert.Equal(t, int64(2), atomic.LoadInt64(&failingHits))
+	select {
+	case update := <-updates:
+		assert.False(t, update.IsHealthy)
+		assert.Equal(t, models.SourceOutlier, update.Source)
+	case <-time.After(time.Second):
+		t.Fatal("ejection was not published to subscribers")
+	}
+
+	// Успешная активная проверка не возвращает исключенный бэкенд раньше срока
+	require.NoError(t, route.registry.UpdateHealth(models.BackendStatus{Id: failing, IsHealthy: true}))
+	select {
+	case update := <-updates:
+		t.Fatalf("unexpected update during ejection: %+v", update)
+	case <-time.After(100 * time.Millisecond):
+	}
+
+	// По окончании исключения бэкенд возвращается через реестр
+	select {
+	case update := <-updates:
+		assert.True(t, update.IsHealthy)
+	case <-time.After(time.Second):
+		t.Fatal("backend was not re-admitted")
+	}
+}
+
+func TestOutlierDetectionRespectsMaxEjectionPercent(t *testing.T) {
+	var hits int64
+	failing := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt64(&hits, 1)
+		w.WriteHeader(http.StatusBadGateway)
+	})
+	handler := newTestRoute(t, loadBalancer.RouteConfig{
+		Path:  "/outlier",
+		Retry: loadBalancer.RetryPolicy{MaxAttempts: 1},
+		OutlierDetection: loadBalancer.OutlierDetectionConfig{
+			Enabled:                  true,
+			ConsecutiveGatewayErrors: 1,
+			BaseEjectionTime:         time.Minute,
+			MaxEjectionPercent:       50,
+		},
+	}, failing, failing).handler
+
+	// Оба бэкенда отказывают, но исключить можно только половину пула
+	for i := 0; i < 6; i++ {
+		handler.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/outlier", nil))
+	}
+	assert.Equal(t, int64(6), atomic.LoadInt64(&hits))
+}
+
+func TestOutlierDetectionKeepsSingleBackend(t *testing.T) {
+	var hits int64
+	route := newTestRoute(t, loadBalancer.RouteConfig{
+		Path:  "/outlier",
+		Retry: loadBalancer.RetryPolicy{MaxAttempts: 1},
+		OutlierDetection: loadBalancer.OutlierDetectionConfig{
+			Enabled:                  true,
+			ConsecutiveGatewayErrors: 1,
+			BaseEjectionTime:         time.Minute,
+		},
+	}, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt64(&hits, 1)
+		w.WriteHeader(http.StatusBadGateway)
+	}))
+
+	// Исключение единственного бэкенда оставило бы маршрут без бэкендов
+	for i := 0; i < 5; i++ {
+		route.handler.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/outlier", nil))
+	}
+	assert.Equal(t, int64(5), atomic.LoadInt64(&hits))
+	assert.False(t, route.registry.Health(route.backends[0].Id).Ejected)
+}
+
+func TestOutlierDetectionConfigValidation(t *testing.T) {
+	assert.NoError(t, loadBalancer.OutlierDetectionConfig{Enabled: true, LatencyFactor: 3}.Validate())
+	assert.Error(t, loadBalancer.OutlierDetectionConfig{Enabled: true, LatencyFactor: 0.5}.Validate())
+	assert.Error(t, loadBalancer.OutlierDetectionConfig{Enabled: true, MaxEjectionPercent: 120}.Validate())
+}
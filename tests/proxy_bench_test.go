@@ -0,0 +1,89 @@
+package integration
+
+import (
+	"bytes"
+	"io"
+	"lb/internal/modules/loadBalancer"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+// newBenchHandler поднимает h2c-бэкенд, который дочитывает тело запроса,
+// и обработчик балансировщика с единственным здоровым бэкендом
+func newBenchHandler(b *testing.B, route loadBalancer.RouteConfig) http.Handler {
+	b.Helper()
+	route.Path = "/bench"
+	return newTestRoute(b, route, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		io.Copy(io.Discard, r.Body)
+		w.Write([]byte("ok"))
+	})).handler
+}
+
+// benchmarkUpload проксирует тело заданного размера и показывает память на запрос
+func benchmarkUpload(b *testing.B, route loadBalancer.RouteConfig, size int) {
+	handler := newBenchHandler(b, route)
+	payload := bytes.Repeat([]byte("x"), size)
+
+	b.ReportAllocs()
+	b.SetBytes(int64(size))
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		req := httptest.NewRequest(http.MethodPost, "/bench/upload", bytes.NewReader(payload))
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, req)
+		if rec.Code != http.StatusOK {
+			b.Fatalf("unexpected status %d: %s", rec.Code, rec.Body.String())
+		}
+	}
+}
+
+func BenchmarkProxySmallBody(b *testing.B) {
+	benchmarkUpload(b, loadBalancer.RouteConfig{}, 1<<10)
+}
+
+func BenchmarkProxyLargeBody(b *testing.B) {
+	benchmarkUpload(b, loadBalancer.RouteConfig{}, 32<<20)
+}
+
+func BenchmarkProxyLargeBodySpooled(b *testing.B) {
+	benchmarkUpload(b, loadBalancer.RouteConfig{
+		BodyBuffering: loadBalancer.BodyBufferingConfig{SpoolToDisk: true},
+	}, 32<<20)
+}
+
+// BenchmarkProxyTimeToFirstByte измеряет, через сколько бэкенд получает первые байты
+// крупной загрузки, пока клиент еще не закончил передачу тела
+func BenchmarkProxyTimeToFirstByte(b *testing.B) {
+	firstByte := make(chan struct{}, 1)
+	handler := newTestRoute(b, loadBalancer.RouteConfig{Path: "/ttfb"}, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		buf := make([]byte, 1)
+		if _, err := io.ReadFull(r.Body, buf); err == nil {
+			firstByte <- struct{}{}
+		}
+		io.Copy(io.Discard, r.Body)
+	})).handler
+
+	chunk := bytes.Repeat([]byte("x"), 256<<10)
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		pr, pw := io.Pipe()
+		req := httptest.NewRequest(http.MethodPost, "/ttfb/upload", pr)
+		req.ContentLength = -1
+		done := make(chan struct{})
+		go func() {
+			handler.ServeHTTP(httptest.NewRecorder(), req)
+			close(done)
+		}()
+
+		// Клиент отправил только первую часть тела - бэкенд уже должен ее получать
+		go pw.Write(chunk)
+		<-firstByte
+
+		b.StopTimer()
+		pw.Close()
+		<-done
+		b.StartTimer()
+	}
+}
@@ -0,0 +1,193 @@
+package integration
+
+import (
+	"context"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+	"golang.org/x/net/http2"
+	"golang.org/x/net/http2/h2c"
+	"lb/internal/modules/backends"
+	"lb/internal/modules/backends/models"
+	"lb/internal/modules/loadBalancer"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// newRetryRoute поднимает маршрут с бэкендами, отвечающими заданными статусами,
+// и возвращает обработчик вместе со счетчиками обращений к каждому бэкенду
+func newRetryRoute(t *testing.T, policy loadBalancer.RetryPolicy, statuses ...int) (*loadBalancer.LoadBalancerHandler, []*int64) {
+	hits := make([]*int64, len(statuses))
+	handlers := make([]http.Handler, len(statuses))
+	for i, status := range statuses {
+		counter := new(int64)
+		hits[i] = counter
+		handlers[i] = http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			atomic.AddInt64(counter, 1)
+			w.WriteHeader(status)
+		})
+	}
+	route := newTestRoute(t, loadBalancer.RouteConfig{Path: "/retry", Retry: policy}, handlers...)
+	return route.handler, hits
+}
+
+func TestRetryGoesToDifferentBackend(t *testing.T) {
+	handler, hits := newRetryRoute(t, loadBalancer.RetryPolicy{BackoffBase: time.Millisecond},
+		http.StatusServiceUnavailable, http.StatusOK)
+
+	const requests = 4
+	for i := 0; i < requests; i++ {
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/retry", nil))
+		assert.Equal(t, http.StatusOK, rec.Code)
+	}
+
+	// Каждый раз, когда первым выбран отказавший бэкенд, повтор уходит на второй
+	failed := atomic.LoadInt64(hits[0])
+	assert.Positive(t, failed)
+	assert.Equal(t, int64(requests), atomic.LoadInt64(hits[1]))
+
+	stats := handler.RetryStats()
+	assert.Equal(t, uint64(requests), stats.Requests)
+	assert.Equal(t, uint64(requests+failed), stats.Attempts)
+	assert.Equal(t, uint64(failed), stats.Retries)
+	assert.Zero(t, stats.Exhausted)
+}
+
+func TestRetryExhaustedReturnsLastResponse(t *testing.T) {
+	handler, hits := newRetryRoute(t, loadBalancer.RetryPolicy{MaxAttempts: 3, BackoffBase: time.Millisecond},
+		http.StatusServiceUnavailable, http.StatusBadGateway)
+
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/retry", nil))
+
+	assert.Contains(t, []int{http.StatusServiceUnavailable, http.StatusBadGateway}, rec.Code)
+	// Оба бэкенда опробованы, третья попытка - снова на одном из них
+	assert.Equal(t, int64(3), atomic.LoadInt64(hits[0])+atomic.LoadInt64(hits[1]))
+	assert.Positive(t, atomic.LoadInt64(hits[0]))
+	assert.Positive(t, atomic.LoadInt64(hits[1]))
+	assert.Equal(t, uint64(1), handler.RetryStats().Exhausted)
+}
+
+func TestRetrySkipsNonIdempotentRequests(t *testing.T) {
+	handler, hits := newRetryRoute(t, loadBalancer.RetryPolicy{IdempotentOnly: true, BackoffBase: time.Millisecond},
+		http.StatusServiceUnavailable)
+
+	// POST без Idempotency-Key отправляется один раз
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, httptest.NewRequest(http.MethodPost, "/retry", strings.NewReader("payload")))
+	assert.Equal(t, http.StatusServiceUnavailable, rec.Code)
+	assert.Equal(t, int64(1), atomic.LoadInt64(hits[0]))
+
+	// С ключом идемпотентности повторяется; единственный бэкенд получает все попытки
+	req := httptest.NewRequest(http.MethodPost, "/retry", strings.NewReader("payload"))
+	req.Header.Set("Idempotency-Key", "order-42")
+	handler.ServeHTTP(httptest.NewRecorder(), req)
+	assert.Equal(t, int64(1+3), atomic.LoadInt64(hits[0]))
+}
+
+func TestRetryOnlyListedStatuses(t *testing.T) {
+	handler, hits := newRetryRoute(t, loadBalancer.RetryPolicy{
+		RetryOnStatus: []int{http.StatusServiceUnavailable},
+		BackoffBase:   time.Millisecond,
+	}, http.StatusInternalServerError)
+
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/retry", nil))
+	assert.Equal(t, http.StatusInternalServerError, rec.Code)
+	assert.Equal(t, int64(1), atomic.LoadInt64(hits[0]))
+	assert.Zero(t, handler.RetryStats().Retries)
+}
+
+func TestRetryPolicyValidation(t *testing.T) {
+	assert.NoError(t, loadBalancer.RetryPolicy{}.Validate())
+	assert.Error(t, loadBalancer.RetryPolicy{RetryOnErrors: []string{"dns"}}.Validate())
+	assert.Error(t, loadBalancer.RetryPolicy{RetryOnStatus: []int{700}}.Validate())
+	assert.Error(t, loadBalancer.RetryPolicy{MaxAttempts: -1}.Validate())
+	assert.Error(t, loadBalancer.RetryPolicy{Budget: loadBalancer.RetryBudget{Ratio: -0.5}}.Validate())
+	assert.Error(t, loadBalancer.RetryPolicy{Budget: loadBalancer.RetryBudget{Ratio: 0.2, Window: 100 * time.Millisecond}}.Validate())
+}
+
+func TestRetryBudgetLimitsRetries(t *testing.T) {
+	handler, hits := newRetryRoute(t, loadBalancer.RetryPolicy{
+		MaxAttempts: 2,
+		BackoffBase: time.Millisecond,
+		Budget:      loadBalancer.RetryBudget{Ratio: 0.1},
+	}, http.StatusServiceUnavailable)
+
+	const requests = 20
+	for i := 0; i < requests; i++ {
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/retry", nil))
+		assert.Equal(t, http.StatusServiceUnavailable, rec.Code)
+	}
+
+	// Без бюджета было бы 40 обращений; с бюджетом 10% повторов - не больше 22
+	stats := handler.RetryStats()
+	assert.Equal(t, uint64(requests), stats.Retries+stats.Denied)
+	assert.LessOrEqual(t, stats.Retries, uint64(requests/10+1))
+	assert.Equal(t, int64(requests)+int64(stats.Retries), atomic.LoadInt64(hits[0]))
+}
+
+func TestRetryBudgetMinimumPerSecond(t *testing.T) {
+	handler, _ := newRetryRoute(t, loadBalancer.RetryPolicy{
+		MaxAttempts: 2,
+		BackoffBase: time.Millisecond,
+		Budget:      loadBalancer.RetryBudget{MinRetriesPerSecond: 1, Window: time.Second},
+	}, http.StatusServiceUnavailable)
+
+	// Запросы должны уложиться в одну секунду окна
+	time.Sleep(time.Until(time.Now().Truncate(time.Second).Add(time.Second)))
+	for i := 0; i < 5; i++ {
+		handler.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/retry", nil))
+	}
+
+	// Окно в одну секунду и минимум 1 повтор в секунду: разрешен один повтор
+	stats := handler.RetryStats()
+	assert.Equal(t, uint64(1), stats.Retries)
+	assert.Equal(t, uint64(4), stats.Denied)
+}
+
+func TestRetryCancelDuringBackoffKeepsConnectionCount(t *testing.T) {
+	logger := zap.NewNop()
+	registry := backends.NewBackendRegistry()
+	backend := httptest.NewServer(h2c.NewHandler(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}), &http2.Server{}))
+	defer backend.Close()
+
+	failing := models.Backend{Id: 9531, URL: backend.URL, Health: "/health"}
+	route := loadBalancer.RouteConfig{
+		Path:     "/retry",
+		Retry:    loadBalancer.RetryPolicy{MaxAttempts: 2, BackoffBase: time.Second},
+		Backends: []models.Backend{failing},
+	}
+	registry.AddBackendToRegistry(failing)
+	updates := registry.Subscribe(failing.Id)
+	strategy := loadBalancer.NewLeastConnectionsStrategy()
+	handler, err := loadBalancer.NewLBHandler(route, registry, []<-chan models.BackendStatus{updates}, strategy, logger)
+	require.NoError(t, err)
+	require.NoError(t, registry.UpdateHealth(models.BackendStatus{Id: failing.Id, IsHealthy: true}))
+	waitRouteHealthy(t, handler, failing.Id)
+
+	// Клиент уходит, пока балансировщик ждет перед повтором
+	ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+	defer cancel()
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/retry", nil).WithContext(ctx))
+	require.ErrorIs(t, ctx.Err(), context.DeadlineExceeded)
+
+	// Счетчик бэкенда вернулся к нулю: с одним запросом в обработке
+	// он проигрывает свободному бэкенду, а не уходит в минус
+	idle := &models.Backend{Id: 9532, URL: "idle"}
+	strategy.RequestStarted(&failing)
+	for i := 0; i < 20; i++ {
+		next, err := strategy.GetNextBackend([]*models.Backend{&failing, idle})
+		require.NoError(t, err)
+		assert.Equal(t, idle.Id, next.Id)
+	}
+}
@@ -0,0 +1,104 @@
+package integration
+
+import (
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+	"golang.org/x/net/http2"
+	"golang.org/x/net/http2/h2c"
+	"lb/internal/modules/backends"
+	"lb/internal/modules/backends/models"
+	"lb/internal/modules/healthchecker"
+	"lb/internal/modules/loadBalancer"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// testBackendIds выдает Id бэкендам тестовых маршрутов
+var testBackendIds atomic.Uint64
+
+// testRoute - маршрут балансировщика, поднятый для теста
+type testRoute struct {
+	handler  *loadBalancer.LoadBalancerHandler
+	lbMap    map[string]*loadBalancer.LoadBalancerHandler
+	registry *backends.BackendRegistry
+	backends []models.Backend // бэкенды маршрута с выданными Id и URL
+}
+
+// newTestRoute поднимает h2c-бэкенд на каждый обработчик из handlers и маршрут route с ними.
+// Бэкенды получают уникальные Id. Если route.Backends задан, его элементы служат шаблонами
+// бэкендов (пул, вес, протокол) и по порядку дополняются Id и URL;
+// URL шаблона, если задан, становится базовым путем бэкенда.
+// Маршрут возвращается, когда балансировщик считает все бэкенды здоровыми.
+func newTestRoute(t testing.TB, route loadBalancer.RouteConfig, handlers ...http.Handler) *testRoute {
+	t.Helper()
+	logger := zap.NewNop()
+	registry := backends.NewBackendRegistry()
+	hc := healthchecker.NewHealthChecker(time.Minute, time.Minute, registry, http.DefaultClient, logger)
+
+	templates := route.Backends
+	require.True(t, len(templates) == 0 || len(templates) == len(handlers), "backend templates must match handlers")
+	route.Backends = make([]models.Backend, len(handlers))
+	for i, handler := range handlers {
+		server := httptest.NewServer(h2c.NewHandler(handler, &http2.Server{}))
+		t.Cleanup(server.Close)
+		if len(templates) > 0 {
+			route.Backends[i] = templates[i]
+		}
+		route.Backends[i].Id = testBackendIds.Add(1)
+		route.Backends[i].URL = server.URL + route.Backends[i].URL
+		route.Backends[i].Health = "/health"
+	}
+
+	lbMap, err := loadBalancer.CreateLoadBalancers([]loadBalancer.RouteConfig{route}, registry, hc, logger)
+	require.NoError(t, err)
+	tr := &testRoute{handler: lbMap[route.Path], lbMap: lbMap, registry: registry, backends: route.Backends}
+	for _, backend := range route.Backends {
+		require.NoError(t, registry.UpdateHealth(models.BackendStatus{Id: backend.Id, IsHealthy: true}))
+	}
+	waitRouteHealthy(t, tr.handler, tr.ids()...)
+	return tr
+}
+
+// ids возвращает Id бэкендов маршрута в порядке обработчиков
+func (tr *testRoute) ids() []uint64 {
+	ids := make([]uint64, len(tr.backends))
+	for i, backend := range tr.backends {
+		ids[i] = backend.Id
+	}
+	return ids
+}
+
+// setHealthy меняет состояние бэкенда и ждет, пока его увидит балансировщик
+func (tr *testRoute) setHealthy(t testing.TB, id uint64, healthy bool) {
+	t.Helper()
+	require.NoError(t, tr.registry.UpdateHealth(models.BackendStatus{Id: id, IsHealthy: healthy}))
+	require.Eventually(t, func() bool {
+		return isHealthy(tr.handler, id) == healthy
+	}, time.Second, 5*time.Millisecond)
+}
+
+// waitRouteHealthy ждет, пока балансировщик начнет выбирать среди всех бэкендов ids
+func waitRouteHealthy(t testing.TB, handler *loadBalancer.LoadBalancerHandler, ids ...uint64) {
+	t.Helper()
+	require.Eventually(t, func() bool {
+		for _, id := range ids {
+			if !isHealthy(handler, id) {
+				return false
+			}
+		}
+		return true
+	}, time.Second, 5*time.Millisecond)
+}
+
+// isHealthy сообщает, выбирает ли балансировщик бэкенд id
+func isHealthy(handler *loadBalancer.LoadBalancerHandler, id uint64) bool {
+	for _, backend := range handler.HealthyBackends() {
+		if backend.Id == id {
+			return true
+		}
+	}
+	return false
+}
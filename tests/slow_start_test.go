@@ -0,0 +1,69 @@
+package integration
+
+import (
+	"github.com/stretchr/testify/assert"
+	"lb/internal/modules/loadBalancer"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"testing"
+	"time"
+)
+
+// newSlowStartRoute поднимает маршрут из двух бэкендов с разогревом window.
+// Первый бэкенд выбывает и возвращается в строй, то есть начинает разогрев.
+// Бэкенды отвечают своим номером.
+func newSlowStartRoute(t *testing.T, algorithm string, window time.Duration) *loadBalancer.LoadBalancerHandler {
+	handlers := make([]http.Handler, 2)
+	for i := range handlers {
+		handlers[i] = http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.Write([]byte(strconv.Itoa(i)))
+		})
+	}
+	// Первое появление бэкендов разогревом не считается
+	route := newTestRoute(t, loadBalancer.RouteConfig{Path: "/warm", Algorithm: algorithm, SlowStart: window}, handlers...)
+	route.setHealthy(t, route.backends[0].Id, false)
+	route.setHealthy(t, route.backends[0].Id, true)
+	return route.handler
+}
+
+// warmingShare отправляет n запросов и возвращает долю, обслуженную бэкендом с номером index
+func warmingShare(handler http.Handler, index int, n int) float64 {
+	served := 0
+	for i := 0; i < n; i++ {
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/warm", nil))
+		if rec.Body.String() == strconv.Itoa(index) {
+			served++
+		}
+	}
+	return float64(served) / float64(n)
+}
+
+func TestSlowStartRampWeightedRoundRobin(t *testing.T) {
+	handler := newSlowStartRoute(t, "weighted_round_robin", time.Second)
+
+	// В начале окна бэкенд получает около 10% своего веса: 0.1 / (0.1 + 1) ≈ 9% трафика
+	assert.Less(t, warmingShare(handler, 0, 100), 0.2)
+
+	// К концу окна вес восстанавливается, и плавный WRR снова делит трафик поровну
+	time.Sleep(time.Second)
+	assert.InDelta(t, 0.5, warmingShare(handler, 0, 100), 0.02)
+}
+
+func TestSlowStartRampRoundRobin(t *testing.T) {
+	handler := newSlowStartRoute(t, "round_robin", 2*time.Second)
+
+	// Стратегия без WeightScaledStrategy получает разогревающийся бэкенд
+	// среди кандидатов лишь с вероятностью, равной его доле веса
+	early := warmingShare(handler, 0, 400)
+	assert.Less(t, early, 0.2)
+
+	// Ближе к концу окна доля растет
+	time.Sleep(1500 * time.Millisecond)
+	late := warmingShare(handler, 0, 400)
+	assert.Greater(t, late, early)
+
+	time.Sleep(time.Second)
+	assert.InDelta(t, 0.5, warmingShare(handler, 0, 100), 0.02)
+}
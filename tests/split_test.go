@@ -0,0 +1,145 @@
+package integration
+
+import (
+	"context"
+	"encoding/json"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+	routes "lb/internal/modules"
+	"lb/internal/modules/backends"
+	"lb/internal/modules/backends/models"
+	"lb/internal/modules/healthchecker"
+	"lb/internal/modules/loadBalancer"
+	"lb/internal/modules/rateLimiter"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+// newSplitRoute поднимает маршрут с пулами stable и canary по одному бэкенду;
+// бэкенд отвечает именем своего пула
+func newSplitRoute(t *testing.T, stable, canary int) *testRoute {
+	route := loadBalancer.RouteConfig{
+		Path:         "/split",
+		Pools:        []loadBalancer.BackendPool{{Name: "stable", Percent: stable}, {Name: "canary", Percent: canary}},
+		PoolOverride: loadBalancer.PoolOverride{Header: "X-Pool", Cookie: "lb_pool"},
+	}
+	var handlers []http.Handler
+	for _, pool := range []string{"stable", "canary"} {
+		route.Backends = append(route.Backends, models.Backend{Pool: pool})
+		handlers = append(handlers, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.Write([]byte(pool))
+		}))
+	}
+	return newTestRoute(t, route, handlers...)
+}
+
+// servedPool возвращает пул, обслуживший запрос
+func servedPool(handler http.Handler, r *http.Request) string {
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, r)
+	return rec.Body.String()
+}
+
+func TestSplitFollowsPercents(t *testing.T) {
+	handler := newSplitRoute(t, 100, 0).handler
+	for i := 0; i < 20; i++ {
+		assert.Equal(t, "stable", servedPool(handler, httptest.NewRequest(http.MethodGet, "/split", nil)))
+	}
+
+	require.NoError(t, handler.SetPoolSplit(map[string]int{"stable": 0, "canary": 100}))
+	for i := 0; i < 20; i++ {
+		assert.Equal(t, "canary", servedPool(handler, httptest.NewRequest(http.MethodGet, "/split", nil)))
+	}
+
+	// Счетчики пулов учитывают каждый обслуженный запрос
+	assert.Equal(t, []loadBalancer.PoolStatus{
+		{Name: "stable", Percent: 0, Requests: 20},
+		{Name: "canary", Percent: 100, Requests: 20},
+	}, handler.Pools())
+}
+
+func TestSplitOverrideByHeaderAndCookie(t *testing.T) {
+	handler := newSplitRoute(t, 100, 0).handler
+
+	req := httptest.NewRequest(http.MethodGet, "/split", nil)
+	req.Header.Set("X-Pool", "canary")
+	assert.Equal(t, "canary", servedPool(handler, req))
+
+	req = httptest.NewRequest(http.MethodGet, "/split", nil)
+	req.AddCookie(&http.Cookie{Name: "lb_pool", Value: "canary"})
+	assert.Equal(t, "canary", servedPool(handler, req))
+
+	// Неизвестный пул в переопределении игнорируется
+	req = httptest.NewRequest(http.MethodGet, "/split", nil)
+	req.Header.Set("X-Pool", "beta")
+	assert.Equal(t, "stable", servedPool(handler, req))
+}
+
+func TestSetPoolSplitValidation(t *testing.T) {
+	handler := newSplitRoute(t, 90, 10).handler
+
+	assert.ErrorContains(t, handler.SetPoolSplit(map[string]int{"stable": 100}), "must list all 2 pools")
+	assert.ErrorContains(t, handler.SetPoolSplit(map[string]int{"stable": 50, "beta": 50}), `unknown pool "beta"`)
+	assert.ErrorContains(t, handler.SetPoolSplit(map[string]int{"stable": 110, "canary": -10}), "must not be negative")
+	assert.ErrorContains(t, handler.SetPoolSplit(map[string]int{"stable": 50, "canary": 40}), "sum to 100")
+
+	// Отклоненные изменения не трогают текущее распределение
+	pools := handler.Pools()
+	assert.Equal(t, 90, pools[0].Percent)
+	assert.Equal(t, 10, pools[1].Percent)
+}
+
+func TestAdminSplitsRequiresToken(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	logger := zap.NewNop()
+	lbMap := newSplitRoute(t, 90, 10).lbMap
+	limiter := rateLimiter.NewTokenBucketLimiter(ctx, 100, 30*time.Second, logger)
+	registry := backends.NewBackendRegistry()
+	hc := healthchecker.NewHealthChecker(time.Minute, time.Minute, registry, http.DefaultClient, logger)
+
+	put := func(router http.Handler, authorization string) int {
+		req := httptest.NewRequest(http.MethodPut, "/admin/splits?path=/split", strings.NewReader(`{"stable": 80, "canary": 20}`))
+		if authorization != "" {
+			req.Header.Set("Authorization", authorization)
+		}
+		rec := httptest.NewRecorder()
+		router.ServeHTTP(rec, req)
+		return rec.Code
+	}
+
+	// Без токена admin API только читает
+	readOnly := routes.CreateRouter(ctx, lbMap, limiter, registry, hc, "", logger)
+	assert.Equal(t, http.StatusForbidden, put(readOnly, ""))
+	rec := httptest.NewRecorder()
+	readOnly.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/admin/splits", nil))
+	assert.Equal(t, http.StatusOK, rec.Code)
+
+	// С токеном любой запрос к admin API требует его
+	protected := routes.CreateRouter(ctx, lbMap, limiter, registry, hc, "secret", logger)
+	assert.Equal(t, http.StatusUnauthorized, put(protected, ""))
+	assert.Equal(t, http.StatusUnauthorized, put(protected, "Bearer wrong"))
+	rec = httptest.NewRecorder()
+	protected.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/admin/splits", nil))
+	assert.Equal(t, http.StatusUnauthorized, rec.Code)
+	assert.Equal(t, 90, lbMap["/split"].Pools()[0].Percent)
+
+	assert.Equal(t, http.StatusOK, put(protected, "Bearer secret"))
+	var pools struct {
+		Pools []loadBalancer.PoolStatus `json:"pools"`
+	}
+	req := httptest.NewRequest(http.MethodGet, "/admin/splits", nil)
+	req.Header.Set("Authorization", "Bearer secret")
+	rec = httptest.NewRecorder()
+	protected.ServeHTTP(rec, req)
+	var result []json.RawMessage
+	require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &result))
+	require.Len(t, result, 1)
+	require.NoError(t, json.Unmarshal(result[0], &pools))
+	assert.Equal(t, 80, pools.Pools[0].Percent)
+	assert.Equal(t, 20, pools.Pools[1].Percent)
+}
@@ -0,0 +1,153 @@
+package integration
+
+import (
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"lb/internal/modules/loadBalancer"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// stickyRoute - маршрут с привязкой сессий из двух бэкендов.
+// Бэкенд отвечает своим номером; failing[i] != 0 - бэкенд i отвечает 503.
+type stickyRoute struct {
+	*testRoute
+	failing [2]*int32
+}
+
+func newStickyRoute(t *testing.T, path string, ttl time.Duration) *stickyRoute {
+	sr := &stickyRoute{}
+	handlers := make([]http.Handler, 2)
+	for i := range handlers {
+		failing := new(int32)
+		sr.failing[i] = failing
+		name := strconv.Itoa(i)
+		handlers[i] = http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if atomic.LoadInt32(failing) != 0 {
+				w.WriteHeader(http.StatusServiceUnavailable)
+				return
+			}
+			w.Write([]byte(name))
+		})
+	}
+	sr.testRoute = newTestRoute(t, loadBalancer.RouteConfig{
+		Path:   path,
+		Sticky: loadBalancer.StickyConfig{Enabled: true, TTL: ttl, SigningKey: "test-key"},
+		Retry:  loadBalancer.RetryPolicy{MaxAttempts: 2, RetryOnStatus: []int{http.StatusServiceUnavailable}, BackoffBase: time.Millisecond},
+	}, handlers...)
+	return sr
+}
+
+// do отправляет запрос с cookie привязки (если задана) и возвращает
+// номер обслужившего бэкенда и выданную cookie (nil - привязка не менялась)
+func (sr *stickyRoute) do(t *testing.T, path string, affinity *http.Cookie) (string, *http.Cookie) {
+	req := httptest.NewRequest(http.MethodGet, path, nil)
+	if affinity != nil {
+		req.AddCookie(affinity)
+	}
+	rec := httptest.NewRecorder()
+	sr.handler.ServeHTTP(rec, req)
+	require.Equal(t, http.StatusOK, rec.Code)
+
+	cookies := rec.Result().Cookies()
+	require.LessOrEqual(t, len(cookies), 1, "affinity cookie set more than once")
+	if len(cookies) == 0 {
+		return rec.Body.String(), nil
+	}
+	return rec.Body.String(), cookies[0]
+}
+
+// affinityTo возвращает cookie, привязывающую клиента к бэкенду с номером want
+func (sr *stickyRoute) affinityTo(t *testing.T, path, want string) *http.Cookie {
+	for i := 0; i < 4; i++ {
+		served, cookie := sr.do(t, path, nil)
+		require.NotNil(t, cookie)
+		if served == want {
+			return cookie
+		}
+	}
+	t.Fatalf("no request was balanced to backend %s", want)
+	return nil
+}
+
+func TestStickyCookieKeepsBackendAndIsScopedToRoute(t *testing.T) {
+	sr := newStickyRoute(t, "/cart", time.Hour)
+	cookie := sr.affinityTo(t, "/cart", "0")
+	assert.Equal(t, "lb_affinity", cookie.Name)
+	assert.Equal(t, "/cart", cookie.Path)
+	assert.True(t, cookie.HttpOnly)
+
+	// С действующей привязкой клиент остается на своем бэкенде, cookie не переписывается
+	for i := 0; i < 5; i++ {
+		served, renewed := sr.do(t, "/cart", cookie)
+		assert.Equal(t, "0", served)
+		assert.Nil(t, renewed)
+	}
+}
+
+func TestStickyCookieTamperingIsIgnored(t *testing.T) {
+	sr := newStickyRoute(t, "/cart", time.Hour)
+	cookie := sr.affinityTo(t, "/cart", "0")
+
+	// Любое изменение значения ломает подпись: привязка игнорируется и выдается заново
+	for _, value := range []string{
+		"x" + cookie.Value,
+		cookie.Value[:len(cookie.Value)-2],
+		strings.Replace(cookie.Value, ".", ".9", 1),
+	} {
+		_, renewed := sr.do(t, "/cart", &http.Cookie{Name: cookie.Name, Value: value})
+		assert.NotNil(t, renewed, "tampered cookie %q was accepted", value)
+	}
+}
+
+func TestStickyCookieExpires(t *testing.T) {
+	sr := newStickyRoute(t, "/cart", time.Second)
+	cookie := sr.affinityTo(t, "/cart", "0")
+	_, renewed := sr.do(t, "/cart", cookie)
+	assert.Nil(t, renewed)
+
+	// Срок хранится с точностью до секунды
+	time.Sleep(2100 * time.Millisecond)
+	_, renewed = sr.do(t, "/cart", cookie)
+	assert.NotNil(t, renewed)
+}
+
+func TestStickyUnhealthyBackendIsRebalanced(t *testing.T) {
+	sr := newStickyRoute(t, "/cart", time.Hour)
+	cookie := sr.affinityTo(t, "/cart", "0")
+
+	sr.setHealthy(t, sr.backends[0].Id, false)
+
+	served, moved := sr.do(t, "/cart", cookie)
+	assert.Equal(t, "1", served)
+	require.NotNil(t, moved)
+
+	// Новая привязка держится и после возвращения прежнего бэкенда
+	sr.setHealthy(t, sr.backends[0].Id, true)
+	served, renewed := sr.do(t, "/cart", moved)
+	assert.Equal(t, "1", served)
+	assert.Nil(t, renewed)
+}
+
+func TestStickyCookieMovesAfterRetry(t *testing.T) {
+	sr := newStickyRoute(t, "/cart", time.Hour)
+	cookie := sr.affinityTo(t, "/cart", "0")
+
+	// Привязанный бэкенд отвечает 503, повтор уходит на второй - туда переезжает и привязка
+	atomic.StoreInt32(sr.failing[0], 1)
+	served, moved := sr.do(t, "/cart", cookie)
+	assert.Equal(t, "1", served)
+	require.NotNil(t, moved)
+
+	atomic.StoreInt32(sr.failing[0], 0)
+	for i := 0; i < 3; i++ {
+		served, renewed := sr.do(t, "/cart", moved)
+		assert.Equal(t, "1", served)
+		assert.Nil(t, renewed)
+	}
+}
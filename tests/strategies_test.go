@@ -0,0 +1,208 @@
+package integration
+
+import (
+	"fmt"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"lb/internal/modules/backends/models"
+	"lb/internal/modules/loadBalancer"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestWeightedRoundRobinStrategy(t *testing.T) {
+	backendsList := []*models.Backend{
+		{Id: 1, URL: "a", Weight: 5},
+		{Id: 2, URL: "b", Weight: 1},
+		{Id: 3, URL: "c", Weight: 1},
+	}
+	strategy := loadBalancer.NewWeightedRoundRobinStrategy()
+
+	// Плавный WRR: на цикле из 7 запросов порядок a a b a c a a
+	var order []string
+	for i := 0; i < 7; i++ {
+		backend, err := strategy.GetNextBackend(backendsList)
+		require.NoError(t, err)
+		order = append(order, backend.URL)
+	}
+	assert.Equal(t, []string{"a", "a", "b", "a", "c", "a", "a"}, order)
+
+	// После выпадения бэкенда веса оставшихся сохраняются
+	counts := make(map[string]int)
+	for i := 0; i < 60; i++ {
+		backend, err := strategy.GetNextBackend(backendsList[:2])
+		require.NoError(t, err)
+		counts[backend.URL]++
+	}
+	assert.Equal(t, 50, counts["a"])
+	assert.Equal(t, 10, counts["b"])
+}
+
+func TestWeightedRoundRobinAlternatingSubsets(t *testing.T) {
+	backendsList := []*models.Backend{
+		{Id: 1, URL: "a", Weight: 3},
+		{Id: 2, URL: "b", Weight: 1},
+		{Id: 3, URL: "c", Weight: 1},
+	}
+	strategy := loadBalancer.NewWeightedRoundRobinStrategy()
+
+	// Запросы попеременно приходят с полным набором и с подмножеством
+	// (например, пока автомат бэкенда "c" занят пробным запросом)
+	full := make(map[string]int)
+	subset := make(map[string]int)
+	for i := 0; i < 40; i++ {
+		backend, err := strategy.GetNextBackend(backendsList)
+		require.NoError(t, err)
+		full[backend.URL]++
+		backend, err = strategy.GetNextBackend(backendsList[:2])
+		require.NoError(t, err)
+		subset[backend.URL]++
+	}
+
+	// Каждый набор сохраняет точное соотношение весов
+	assert.Equal(t, map[string]int{"a": 24, "b": 8, "c": 8}, full)
+	assert.Equal(t, map[string]int{"a": 30, "b": 10}, subset)
+}
+
+func TestLeastConnectionsStrategy(t *testing.T) {
+	backendsList := []*models.Backend{
+		{Id: 1, URL: "a"},
+		{Id: 2, URL: "b"},
+	}
+	strategy := loadBalancer.NewLeastConnectionsStrategy()
+
+	// Пока на "a" висит запрос, все новые запросы уходят на "b"
+	strategy.RequestStarted(backendsList[0])
+	for i := 0; i < 10; i++ {
+		backend, err := strategy.GetNextBackend(backendsList)
+		require.NoError(t, err)
+		assert.Equal(t, "b", backend.URL)
+	}
+
+	// При равной загрузке выбор распределяется между обоими бэкендами
+	strategy.RequestFinished(backendsList[0])
+	counts := make(map[string]int)
+	for i := 0; i < 200; i++ {
+		backend, err := strategy.GetNextBackend(backendsList)
+		require.NoError(t, err)
+		counts[backend.URL]++
+	}
+	assert.Greater(t, counts["a"], 0)
+	assert.Greater(t, counts["b"], 0)
+}
+
+func TestP2CEWMAStrategy(t *testing.T) {
+	backendsList := []*models.Backend{
+		{Id: 1, URL: "slow"},
+		{Id: 2, URL: "fast"},
+		{Id: 3, URL: "failing"},
+	}
+	strategy := loadBalancer.NewP2CEWMAStrategy()
+	strategy.ObserveResult(backendsList[0], 500*time.Millisecond, false)
+	strategy.ObserveResult(backendsList[1], 10*time.Millisecond, false)
+	strategy.ObserveResult(backendsList[2], time.Millisecond, true)
+
+	// Из любой пары выигрывает бэкенд с лучшей оценкой, поэтому "failing" не выбирается никогда
+	counts := make(map[string]int)
+	for i := 0; i < 300; i++ {
+		backend, err := strategy.GetNextBackend(backendsList)
+		require.NoError(t, err)
+		counts[backend.URL]++
+	}
+	assert.Zero(t, counts["failing"])
+	assert.Greater(t, counts["fast"], counts["slow"])
+}
+
+func TestConsistentHashStrategy(t *testing.T) {
+	backendsList := []*models.Backend{
+		{Id: 1, URL: "http://a"},
+		{Id: 2, URL: "http://b"},
+		{Id: 3, URL: "http://c"},
+		{Id: 4, URL: "http://d"},
+	}
+	strategy, err := loadBalancer.NewConsistentHashStrategy("header:X-User", 0)
+	require.NoError(t, err)
+
+	pick := func(list []*models.Backend, key string) string {
+		r := httptest.NewRequest(http.MethodGet, "/", nil)
+		r.Header.Set("X-User", key)
+		backend, err := strategy.GetBackendForRequest(r, list)
+		require.NoError(t, err)
+		return backend.URL
+	}
+
+	const keys = 2000
+	before := make([]string, keys)
+	for i := range before {
+		before[i] = pick(backendsList, fmt.Sprintf("user-%d", i))
+	}
+
+	// Убираем один бэкенд: переехать должны только его ключи
+	remaining := backendsList[1:]
+	moved := 0
+	for i := range before {
+		after := pick(remaining, fmt.Sprintf("user-%d", i))
+		if after != before[i] {
+			assert.Equal(t, "http://a", before[i], "key moved off a healthy backend")
+			moved++
+		}
+	}
+	assert.InDelta(t, keys/len(backendsList), moved, keys/10)
+
+	_, err = loadBalancer.NewConsistentHashStrategy("header", 0)
+	assert.Error(t, err)
+}
+
+func TestConsistentHashAlternatingSubsets(t *testing.T) {
+	backendsList := []*models.Backend{
+		{Id: 1, URL: "http://a"},
+		{Id: 2, URL: "http://b"},
+		{Id: 3, URL: "http://c"},
+		{Id: 4, URL: "http://d"},
+	}
+	strategy, err := loadBalancer.NewConsistentHashStrategy("header:X-User", 0)
+	require.NoError(t, err)
+	r := httptest.NewRequest(http.MethodGet, "/", nil)
+	r.Header.Set("X-User", "user-42")
+	pick := func(list []*models.Backend) string {
+		backend, err := strategy.GetBackendForRequest(r, list)
+		require.NoError(t, err)
+		return backend.URL
+	}
+
+	// Запросы попеременно приходят с полным набором и с подмножеством (например, пул)
+	full, subset := pick(backendsList), pick(backendsList[:3])
+	for i := 0; i < 10; i++ {
+		assert.Equal(t, full, pick(backendsList))
+		assert.Equal(t, subset, pick(backendsList[:3]))
+	}
+
+	// Кольца обоих наборов берутся из кэша, а не строятся заново на каждый запрос
+	allocs := testing.AllocsPerRun(100, func() {
+		pick(backendsList)
+		pick(backendsList[:3])
+	})
+	assert.Less(t, allocs, 50.0)
+}
+
+func TestStrategyRegistry(t *testing.T) {
+	strategy, err := loadBalancer.NewStrategy(loadBalancer.RouteConfig{Path: "/api"})
+	require.NoError(t, err)
+	assert.IsType(t, &loadBalancer.RoundRobinAlgorithm{}, strategy)
+
+	_, err = loadBalancer.NewStrategy(loadBalancer.RouteConfig{Path: "/api", Algorithm: "random"})
+	assert.ErrorContains(t, err, `unknown balancing algorithm "random"`)
+
+	_, err = loadBalancer.NewStrategy(loadBalancer.RouteConfig{
+		Algorithm:        "p2c_ewma",
+		AlgorithmOptions: map[string]string{"decay": "2"},
+	})
+	assert.Error(t, err)
+
+	loadBalancer.RegisterStrategy("random", func(loadBalancer.RouteConfig) (loadBalancer.LoadBalancingStrategy, error) {
+		return loadBalancer.NewRoundRobinStrategy(), nil
+	})
+	assert.Contains(t, loadBalancer.StrategyNames(), "random")
+}
@@ -0,0 +1,174 @@
+package integration
+
+import (
+	"bufio"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"io"
+	"lb/internal/modules/loadBalancer"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+// newStreamingRoute поднимает маршрут /events с одним бэкендом и настройками потоковой
+// передачи streaming и возвращает обработчик маршрута
+func newStreamingRoute(t *testing.T, streaming loadBalancer.StreamingConfig, backendHandler http.HandlerFunc) http.Handler {
+	return newTestRoute(t, loadBalancer.RouteConfig{Path: "/events", Streaming: streaming}, backendHandler).handler
+}
+
+func TestServerSentEventsFlushedImmediately(t *testing.T) {
+	release := make(chan struct{})
+	handler := newStreamingRoute(t, loadBalancer.StreamingConfig{}, func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/event-stream")
+		w.Write([]byte("data: first\n\n"))
+		w.(http.Flusher).Flush()
+		// Второе событие уходит только после того, как клиент получил первое
+		<-release
+		w.Write([]byte("data: second\n\n"))
+	})
+
+	front := httptest.NewServer(handler)
+	defer front.Close()
+
+	start := time.Now()
+	resp, err := http.Get(front.URL + "/events")
+	require.NoError(t, err)
+	defer resp.Body.Close()
+
+	reader := bufio.NewReader(resp.Body)
+	lines := make(chan string)
+	go func() {
+		for {
+			line, err := reader.ReadString('\n')
+			if err != nil {
+				close(lines)
+				return
+			}
+			lines <- line
+		}
+	}()
+
+	select {
+	case line := <-lines:
+		assert.Equal(t, "data: first\n", line)
+		assert.Less(t, time.Since(start), 2*time.Second, "first event was held back")
+	case <-time.After(2 * time.Second):
+		t.Fatal("first event was not flushed to the client")
+	}
+	close(release)
+}
+
+func TestStreamIdleTimeoutEndsOnlyStalledStream(t *testing.T) {
+	release := make(chan struct{})
+	defer close(release)
+	handler := newStreamingRoute(t, loadBalancer.StreamingConfig{IdleTimeout: 200 * time.Millisecond},
+		func(w http.ResponseWriter, r *http.Request) {
+			w.Header().Set("Content-Type", "text/event-stream")
+			if r.URL.Path == "/events/stalled" {
+				// Одно событие, затем бэкенд замолкает
+				w.Write([]byte("data: first\n\n"))
+				w.(http.Flusher).Flush()
+				select {
+				case <-release:
+				case <-r.Context().Done():
+				}
+				return
+			}
+			// Активный поток дольше таймаута простоя, но паузы между событиями короче него
+			for i := 0; i < 12; i++ {
+				w.Write([]byte("data: tick\n\n"))
+				w.(http.Flusher).Flush()
+				time.Sleep(50 * time.Millisecond)
+			}
+		})
+	front := httptest.NewServer(handler)
+	defer front.Close()
+
+	stalled := make(chan time.Duration, 1)
+	go func() {
+		start := time.Now()
+		resp, err := http.Get(front.URL + "/events/stalled")
+		if !assert.NoError(t, err) {
+			stalled <- 0
+			return
+		}
+		defer resp.Body.Close()
+		body, _ := io.ReadAll(resp.Body)
+		assert.Equal(t, "data: first\n\n", string(body))
+		stalled <- time.Since(start)
+	}()
+
+	start := time.Now()
+	resp, err := http.Get(front.URL + "/events/active")
+	require.NoError(t, err)
+	body, err := io.ReadAll(resp.Body)
+	resp.Body.Close()
+	require.NoError(t, err)
+	assert.Equal(t, strings.Repeat("data: tick\n\n", 12), string(body))
+	assert.Greater(t, time.Since(start), 500*time.Millisecond)
+
+	// Замолкший поток оборван по таймауту простоя, не дожидаясь бэкенда
+	select {
+	case elapsed := <-stalled:
+		assert.Less(t, elapsed, time.Second)
+	case <-time.After(2 * time.Second):
+		t.Fatal("stalled stream was not ended by the idle timeout")
+	}
+}
+
+func TestFlushIntervalAppliesToNonStreamingResponses(t *testing.T) {
+	// firstPart читает начало ответа с известной длиной, пока бэкенд держит остаток.
+	// Фронт работает по HTTP/2: его сервер буферизует ответ до явного сброса.
+	firstPart := func(streaming loadBalancer.StreamingConfig) (string, bool) {
+		release := make(chan struct{})
+		handler := newStreamingRoute(t, streaming, func(w http.ResponseWriter, r *http.Request) {
+			w.Header().Set("Content-Type", "application/octet-stream")
+			w.Header().Set("Content-Length", "10")
+			w.Write([]byte("hello"))
+			w.(http.Flusher).Flush()
+			select {
+			case <-release:
+			case <-r.Context().Done():
+				return
+			}
+			w.Write([]byte("world"))
+		})
+		front := httptest.NewUnstartedServer(handler)
+		front.EnableHTTP2 = true
+		front.StartTLS()
+		defer front.Close()
+		defer close(release)
+
+		read := make(chan string, 1)
+		go func() {
+			resp, err := front.Client().Get(front.URL + "/events/file")
+			if !assert.NoError(t, err) {
+				return
+			}
+			defer resp.Body.Close()
+			assert.Equal(t, "HTTP/2.0", resp.Proto)
+			assert.Equal(t, int64(10), resp.ContentLength)
+			buf := make([]byte, 5)
+			n, _ := io.ReadFull(resp.Body, buf)
+			read <- string(buf[:n])
+		}()
+		select {
+		case part := <-read:
+			return part, true
+		case <-time.After(300 * time.Millisecond):
+			return "", false
+		}
+	}
+
+	// С FlushInterval начало ответа приходит клиенту, не дожидаясь остатка
+	part, ok := firstPart(loadBalancer.StreamingConfig{FlushInterval: 20 * time.Millisecond})
+	require.True(t, ok, "first part was not flushed by the flush interval")
+	assert.Equal(t, "hello", part)
+
+	// Без него ответ с известной длиной копится в буфере сервера
+	_, ok = firstPart(loadBalancer.StreamingConfig{})
+	assert.False(t, ok, "first part reached the client without a flush interval")
+}
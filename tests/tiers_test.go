@@ -0,0 +1,58 @@
+package integration
+
+import (
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+	"lb/internal/modules/backends"
+	"lb/internal/modules/backends/models"
+	"lb/internal/modules/healthchecker"
+	"lb/internal/modules/loadBalancer"
+	"net/http"
+	"testing"
+	"time"
+)
+
+func TestPriorityTierFailover(t *testing.T) {
+	logger := zap.NewNop()
+	registry := backends.NewBackendRegistry()
+	hc := healthchecker.NewHealthChecker(time.Minute, time.Minute, registry, http.DefaultClient, logger)
+
+	route := loadBalancer.RouteConfig{
+		Path: "/orders",
+		Groups: []loadBalancer.BackendGroup{
+			{Name: "standby", Priority: 1},
+			{Name: "primary", Priority: 0, MinHealthy: 2},
+		},
+		Backends: []models.Backend{
+			{Id: 101, URL: "http://primary-1", Group: "primary"},
+			{Id: 102, URL: "http://primary-2", Group: "primary"},
+			{Id: 103, URL: "http://standby-1", Group: "standby"},
+		},
+	}
+	lbMap, err := loadBalancer.CreateLoadBalancers([]loadBalancer.RouteConfig{route}, registry, hc, logger)
+	require.NoError(t, err)
+	handler := lbMap["/orders"]
+
+	setHealth := func(id uint64, healthy bool) {
+		require.NoError(t, registry.UpdateHealth(models.BackendStatus{Id: id, IsHealthy: healthy}))
+	}
+	activeGroup := func() string {
+		return handler.Tiers().ActiveGroup
+	}
+
+	setHealth(101, true)
+	setHealth(102, true)
+	setHealth(103, true)
+	assert.Eventually(t, func() bool {
+		status := handler.Tiers()
+		return status.ActiveGroup == "primary" && status.Groups[0].Healthy == 2
+	}, time.Second, 10*time.Millisecond)
+
+	// Один бэкенд primary упал - min_healthy не выполнен, трафик уходит в standby
+	setHealth(102, false)
+	assert.Eventually(t, func() bool { return activeGroup() == "standby" }, time.Second, 10*time.Millisecond)
+
+	setHealth(102, true)
+	assert.Eventually(t, func() bool { return activeGroup() == "primary" }, time.Second, 10*time.Millisecond)
+}
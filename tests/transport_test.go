@@ -0,0 +1,75 @@
+package integration
+
+import (
+	"encoding/pem"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+	"lb/internal/modules/backends"
+	"lb/internal/modules/backends/models"
+	"lb/internal/modules/healthchecker"
+	"lb/internal/modules/loadBalancer"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestBackendTransportTLS(t *testing.T) {
+	server := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(r.Proto))
+	}))
+	defer server.Close()
+
+	caFile := filepath.Join(t.TempDir(), "ca.pem")
+	certPEM := pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: server.Certificate().Raw})
+	require.NoError(t, os.WriteFile(caFile, certPEM, 0o600))
+
+	get := func(backend models.Backend) (*http.Response, error) {
+		transport, err := backends.NewTransport(backend)
+		require.NoError(t, err)
+		return (&http.Client{Transport: transport}).Get(backend.URL)
+	}
+
+	// Без своего CA самоподписанный сертификат не проходит проверку
+	_, err := get(models.Backend{URL: server.URL, Protocol: backends.ProtocolHTTP1})
+	assert.Error(t, err)
+
+	// С CA-бандлом и переопределенным SNI соединение проверяется и устанавливается
+	resp, err := get(models.Backend{
+		URL:      server.URL,
+		Protocol: backends.ProtocolHTTP1,
+		TLS:      models.TLSConfig{CAFile: caFile, ServerName: "example.com"},
+	})
+	require.NoError(t, err)
+	defer resp.Body.Close()
+	assert.Equal(t, "HTTP/1.1", resp.Proto)
+
+	_, err = backends.NewTransport(models.Backend{URL: server.URL, Protocol: "spdy"})
+	assert.ErrorContains(t, err, "unknown protocol")
+}
+
+func TestCreateLoadBalancersRejectsInvalidRoutes(t *testing.T) {
+	create := func(route loadBalancer.RouteConfig) error {
+		registry := backends.NewBackendRegistry()
+		hc := healthchecker.NewHealthChecker(time.Minute, time.Minute, registry, http.DefaultClient, zap.NewNop())
+		_, err := loadBalancer.CreateLoadBalancers([]loadBalancer.RouteConfig{route}, registry, hc, zap.NewNop())
+		return err
+	}
+
+	// Бэкенд с недоступным CA не получает транспорт по умолчанию, маршрут не создается
+	err := create(loadBalancer.RouteConfig{Path: "/tls", Backends: []models.Backend{{
+		URL: "https://backend.internal:8443", Protocol: backends.ProtocolAuto,
+		TLS: models.TLSConfig{CAFile: filepath.Join(t.TempDir(), "missing.pem")},
+	}}})
+	assert.ErrorContains(t, err, `invalid route "/tls"`)
+
+	assert.Error(t, create(loadBalancer.RouteConfig{Path: "/algo", Algorithm: "fastest"}))
+	assert.Error(t, create(loadBalancer.RouteConfig{Path: "/headers", Headers: loadBalancer.ProxyHeadersConfig{Host: "client"}}))
+	assert.Error(t, create(loadBalancer.RouteConfig{Path: "/retry", Retry: loadBalancer.RetryPolicy{MaxAttempts: -1}}))
+	assert.Error(t, create(loadBalancer.RouteConfig{Path: "/check", Backends: []models.Backend{{
+		URL: "http://backend.internal", Check: models.HealthCheck{Method: "POST"},
+	}}}))
+}
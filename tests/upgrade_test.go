@@ -0,0 +1,79 @@
+package integration
+
+import (
+	"bufio"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"io"
+	"lb/internal/modules/loadBalancer"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+// echoUpgrade - бэкенд, который переключается на протокол "echo" и возвращает все полученные байты
+var echoUpgrade = http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+	if r.Header.Get("Upgrade") != "echo" {
+		http.Error(w, "upgrade required", http.StatusUpgradeRequired)
+		return
+	}
+	conn, buf, err := w.(http.Hijacker).Hijack()
+	if err != nil {
+		return
+	}
+	defer conn.Close()
+	buf.WriteString("HTTP/1.1 101 Switching Protocols\r\nConnection: Upgrade\r\nUpgrade: echo\r\n\r\n")
+	buf.Flush()
+	io.Copy(conn, buf)
+})
+
+// dialUpgrade открывает через front туннель с протоколом "echo"
+func dialUpgrade(t *testing.T, front *httptest.Server) (net.Conn, *bufio.Reader) {
+	conn, err := net.Dial("tcp", front.Listener.Addr().String())
+	require.NoError(t, err)
+	_, err = io.WriteString(conn, "GET /ws/chat HTTP/1.1\r\nHost: lb\r\nConnection: Upgrade\r\nUpgrade: echo\r\n\r\n")
+	require.NoError(t, err)
+
+	reader := bufio.NewReader(conn)
+	resp, err := http.ReadResponse(reader, nil)
+	require.NoError(t, err)
+	require.Equal(t, http.StatusSwitchingProtocols, resp.StatusCode)
+	return conn, reader
+}
+
+func TestUpgradePassthrough(t *testing.T) {
+	handler := newTestRoute(t, loadBalancer.RouteConfig{Path: "/ws"}, echoUpgrade).handler
+
+	front := httptest.NewServer(handler)
+	defer front.Close()
+
+	conn, reader := dialUpgrade(t, front)
+	defer conn.Close()
+
+	_, err := io.WriteString(conn, "ping")
+	require.NoError(t, err)
+	reply := make([]byte, 4)
+	_, err = io.ReadFull(reader, reply)
+	require.NoError(t, err)
+	assert.Equal(t, "ping", string(reply))
+}
+
+func TestUpgradedConnectionsCountedPerBackend(t *testing.T) {
+	// Round robin не отслеживает запросы, но туннели все равно учитываются
+	route := newTestRoute(t, loadBalancer.RouteConfig{Path: "/ws", Algorithm: "round_robin"}, echoUpgrade)
+	handler, id := route.handler, route.backends[0].Id
+	front := httptest.NewServer(handler)
+	defer front.Close()
+
+	first, _ := dialUpgrade(t, front)
+	second, _ := dialUpgrade(t, front)
+	require.Eventually(t, func() bool { return handler.UpgradedConnections(id) == 2 }, time.Second, 5*time.Millisecond)
+
+	// Закрытый туннель снимается с учета
+	first.Close()
+	require.Eventually(t, func() bool { return handler.UpgradedConnections(id) == 1 }, time.Second, 5*time.Millisecond)
+	second.Close()
+	require.Eventually(t, func() bool { return handler.UpgradedConnections(id) == 0 }, time.Second, 5*time.Millisecond)
+}